@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gi8lino/go-snapraid/internal/app"
 )
@@ -13,10 +15,13 @@ var (
 )
 
 // main sets up the application context and runs the proxy.
+// SIGINT and SIGTERM cancel the context so a running snapraid child is stopped gracefully.
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	if err := app.Run(ctx, Version, Commit, os.Args[1:], os.Stdout); err != nil {
+		stop()
 		os.Exit(1)
 	}
 }
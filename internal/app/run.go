@@ -2,9 +2,12 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/gi8lino/go-snapraid/internal/config"
@@ -51,58 +54,129 @@ func Run(ctx context.Context, version, commit string, args []string, w io.Writer
 	// Fill in any missing defaults now that we have unmarshaled into cfg.
 	cfg.ApplyDefaults()
 
+	// Apply CLI overrides on top of config; extra snapraid arguments from the CLI
+	// are validated together with the configured ones.
+	flag.ApplyOverrides(&cfg, flags)
+
 	if err := cfg.Validate(); err != nil {
 		logger.Error("Failed to validate config", "error", err, "tag", "runner")
 		return err
 	}
 
-	// Apply CLI overrides on top of config
-	flag.ApplyOverrides(&cfg, flags)
+	// Approve a blocked run instead of running SnapRAID
+	if flags.Approve != "" {
+		return approve(cfg.OutputDir, flags.Approve, logger)
+	}
 
 	// Initialize SnapRAID runner
-	runner := snapraid.NewRunner(
-		cfg.SnapraidConfig,
-		cfg.SnapraidBin,
-		cfg.OutputDir,
-		snapraid.Steps{
-			Touch: *cfg.Steps.Touch,
-			Scrub: *cfg.Steps.Scrub,
-			Smart: *cfg.Steps.Smart,
+	runner := snapraid.NewRunner(snapraid.RunnerOptions{
+		ConfigPath: cfg.SnapraidConfig,
+		BinaryPath: cfg.SnapraidBin,
+		OutputDir:  cfg.OutputDir,
+		Steps: snapraid.Steps{
+			Touch:  *cfg.Steps.Touch,
+			Scrub:  *cfg.Steps.Scrub,
+			Smart:  *cfg.Steps.Smart,
+			Status: *cfg.Steps.Status,
+			Verify: *cfg.Steps.Verify,
 		},
-		snapraid.Thresholds{
-			Add:     *cfg.Thresholds.Add,
-			Remove:  *cfg.Thresholds.Remove,
-			Update:  *cfg.Thresholds.Update,
-			Move:    *cfg.Thresholds.Move,
-			Copy:    *cfg.Thresholds.Copy,
-			Restore: *cfg.Thresholds.Restore,
+		Thresholds: thresholds(cfg.Thresholds, cfg.ThresholdRules, cfg.IgnoreForThresholds),
+		Tolerance:  *cfg.Approval.Tolerance,
+		Timeouts: snapraid.Timeouts{
+			Touch:  cfg.Timeouts.Touch,
+			Diff:   cfg.Timeouts.Diff,
+			Sync:   cfg.Timeouts.Sync,
+			Scrub:  cfg.Timeouts.Scrub,
+			Smart:  cfg.Timeouts.Smart,
+			Status: cfg.Timeouts.Status,
+			Repair: cfg.Timeouts.Repair,
+			Total:  cfg.Timeouts.Total,
 		},
-		*cfg.Scrub.Plan,
-		*cfg.Scrub.OlderThan,
-		flags.DryRun,
-		logger,
-	)
+		Retries: snapraid.Retries{
+			Touch:  retryPolicy(cfg.Retry.Touch),
+			Diff:   retryPolicy(cfg.Retry.Diff),
+			Sync:   retryPolicy(cfg.Retry.Sync),
+			Scrub:  retryPolicy(cfg.Retry.Scrub),
+			Smart:  retryPolicy(cfg.Retry.Smart),
+			Status: retryPolicy(cfg.Retry.Status),
+			Repair: retryPolicy(cfg.Retry.Repair),
+		},
+		Diff: snapraid.DiffOptions{
+			MaxPaths: *cfg.Diff.MaxPaths,
+			SpillDir: cfg.Diff.SpillDir,
+		},
+		Settle: snapraid.Settle{
+			Interval: cfg.Settle.Interval,
+			Attempts: *cfg.Settle.Attempts,
+		},
+		Repair: snapraid.RepairPolicy(cfg.Repair.Policy),
+		Planner: snapraid.ScrubPlanner{
+			TargetDays:  cfg.Scrub.TargetCoverageDays,
+			MaxDuration: cfg.Scrub.MaxDuration,
+		},
+		Filters: snapraid.Filters{
+			Diff:  filter(cfg.Filters.Diff),
+			Check: filter(cfg.Filters.Check),
+			Fix:   filter(cfg.Filters.Fix),
+		},
+		Command: snapraid.CommandOptions{
+			Quiet:     *cfg.Snapraid.Quiet,
+			ExtraArgs: cfg.Snapraid.ExtraArgs,
+		},
+		Force: snapraid.ForcePolicy{
+			ZeroSize:   cfg.Force.ZeroSize,
+			EmptyDisks: cfg.Force.EmptyDisks,
+		},
+		ScrubPlan:        *cfg.Scrub.Plan,
+		ScrubOlderThan:   *cfg.Scrub.OlderThan,
+		GracePeriod:      *cfg.GracePeriod,
+		ProgressInterval: *cfg.ProgressEvery,
+		DryRun:           flags.DryRun,
+	}, logger)
 
 	// Run the SnapRAID pipeline
-	result := runner.Run()
-
-	if result.Error != nil {
-		logger.Error("SnapRAID run failed", "error", result.Error, "tag", "runner")
-		return result.Error
-	}
+	result := runner.Run(ctx)
 
 	// Log change summary
-	if !result.HasChanges() {
+	if result.Cancelled() {
+		logger.Warn("SnapRAID run cancelled", "step", result.FailedStep, "tag", "runner")
+	} else if result.TimedOut() {
+		logger.Warn("SnapRAID run timed out", "step", result.FailedStep, "tag", "runner")
+	} else if result.Fingerprint != "" {
+		logger.Warn("SnapRAID sync blocked by thresholds",
+			"fingerprint", result.Fingerprint,
+			"release_with", "approve "+result.Timestamp,
+			"tag", "runner",
+		)
+	} else if result.Deferred {
+		logger.Warn("SnapRAID sync deferred, changes did not settle",
+			"checks", result.SettleChecks,
+			"tag", "runner",
+		)
+	} else if kind := result.ErrorKind; kind == snapraid.ErrorKindZeroSize || kind == snapraid.ErrorKindEmptyDisk {
+		logger.Warn("SnapRAID sync refused",
+			"kind", kind,
+			"forced", result.ForcedSync,
+			"error", result.Error,
+			"tag", "runner",
+		)
+	} else if !result.HasChanges() {
 		logger.Info("No changes detected")
+	} else if changed := result.ChangedDuringSync; changed != nil {
+		logger.Warn("SnapRAID sync completed, but files changed during sync",
+			"changed", changed.Changes(),
+			"errors", result.Error != nil,
+			"tag", "runner",
+		)
 	} else {
 		logger.Info("SnapRAID sync completed",
 			"equal", result.Result.Equal,
-			"added", len(result.Result.Added),
-			"removed", len(result.Result.Removed),
-			"updated", len(result.Result.Updated),
-			"moved", len(result.Result.Moved),
-			"copied", len(result.Result.Copied),
-			"restored", len(result.Result.Restored),
+			"added", result.Result.Count("added"),
+			"removed", result.Result.Count("removed"),
+			"updated", result.Result.Count("updated"),
+			"moved", result.Result.Count("moved"),
+			"copied", result.Result.Count("copied"),
+			"restored", result.Result.Count("restored"),
 			"errors", result.Error != nil,
 			"tag", "runner",
 		)
@@ -138,6 +212,10 @@ func Run(ctx context.Context, version, commit string, args []string, w io.Writer
 			result,
 			runner.Timestamp,
 			result.Timings,
+			snapraid.SmartLimits{
+				FailureProbability: *cfg.Smart.MaxFailureProbability,
+				Temperature:        *cfg.Smart.MaxTemperature,
+			},
 		)
 		if err != nil {
 			logger.Error("Slack notification failed",
@@ -158,3 +236,89 @@ func Run(ctx context.Context, version, commit string, args []string, w io.Writer
 	logger.Info("All done", "tag", "runner")
 	return nil
 }
+
+// approve marks the blocked run with the given timestamp as approved so the next matching run syncs.
+func approve(outputDir, timestamp string, logger *slog.Logger) error {
+	if outputDir == "" {
+		err := errors.New("output_dir must be set to approve a run")
+		logger.Error("Failed to approve run", "error", err, "tag", "approval")
+		return err
+	}
+
+	approval, err := snapraid.Approve(outputDir, timestamp)
+	if err != nil {
+		logger.Error("Failed to approve run", "error", err, "tag", "approval")
+		return err
+	}
+
+	logger.Info("Run approved, the next matching run will sync",
+		"run", approval.Timestamp,
+		"fingerprint", approval.Fingerprint,
+		"tag", "approval",
+	)
+	return nil
+}
+
+// thresholds converts the config thresholds, rules and ignore globs into their snapraid counterpart.
+func thresholds(global config.Thresholds, rules []config.ThresholdRule, ignore []string) snapraid.Thresholds {
+	t := limits(global)
+	t.Ignore = ignore
+	for _, rule := range rules {
+		t.Rules = append(t.Rules, snapraid.ThresholdRule{
+			Pattern:   rule.Path,
+			Unlimited: rule.Unlimited,
+			Limits:    limits(rule.Thresholds),
+		})
+	}
+	return t
+}
+
+// limits converts config thresholds into snapraid thresholds. Unset categories are disabled.
+func limits(t config.Thresholds) snapraid.Thresholds {
+	get := func(th *config.Threshold) config.Threshold {
+		if th == nil {
+			return config.Threshold{Count: -1}
+		}
+		return *th
+	}
+	add, remove, update := get(t.Add), get(t.Remove), get(t.Update)
+	move, cp, restore := get(t.Move), get(t.Copy), get(t.Restore)
+
+	return snapraid.Thresholds{
+		Add:            add.Count,
+		Remove:         remove.Count,
+		Update:         update.Count,
+		Move:           move.Count,
+		Copy:           cp.Count,
+		Restore:        restore.Count,
+		AddPercent:     add.Percent,
+		RemovePercent:  remove.Percent,
+		UpdatePercent:  update.Percent,
+		MovePercent:    move.Percent,
+		CopyPercent:    cp.Percent,
+		RestorePercent: restore.Percent,
+	}
+}
+
+// retryPolicy converts a validated config retry policy into its snapraid counterpart.
+func retryPolicy(p config.RetryPolicy) snapraid.RetryPolicy {
+	patterns := make([]*regexp.Regexp, 0, len(p.StderrPatterns))
+	for _, pattern := range p.StderrPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern)) // validated by config.Validate
+	}
+	return snapraid.RetryPolicy{
+		MaxAttempts:    p.MaxAttempts,
+		Backoff:        p.Backoff,
+		ExitCodes:      p.ExitCodes,
+		StderrPatterns: patterns,
+	}
+}
+
+// filter converts a config filter into its snapraid counterpart.
+func filter(f config.Filter) snapraid.Filter {
+	return snapraid.Filter{
+		Disks:        f.Disks,
+		ExcludeDisks: f.ExcludeDisks,
+		Paths:        f.Paths,
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/gi8lino/go-snapraid/internal/config"
 	"github.com/gi8lino/go-snapraid/internal/testutils"
+	"github.com/gi8lino/go-snapraid/pkg/snapraid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -67,6 +71,28 @@ snapraid_config: "%s"
 		assert.Contains(t, stdout.String(), "There are differences")
 	})
 
+	t.Run("Sync progress is logged with the default config", func(t *testing.T) {
+		t.Parallel()
+
+		dummyConf := testutils.WriteFile(t, "# dummy snapraid config")
+
+		// Like snapraid, the progress line is only printed without --quiet
+		content := `case "$1" in
+diff) printf "add a.txt\n\n1 equal\n1 added\n" ;;
+sync) case "$*" in *--quiet*) ;; *) printf "47%%, 123 MB, 10 MB/s, CPU 5%%, 0:10 ETA\n" ;; esac ;;
+esac`
+		binPath := testutils.WriteScriptFile(t, content, 0)
+		cfgPath := testutils.WriteFile(t, fmt.Sprintf(`
+snapraid_bin: "%s"
+snapraid_config: "%s"
+`, binPath, dummyConf))
+
+		var stdout bytes.Buffer
+		err := Run(context.Background(), "vTEST", "commit123", []string{"--config", cfgPath}, &stdout)
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "percent=47")
+	})
+
 	t.Run("Diff failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -84,6 +110,36 @@ snapraid_config: "%s"
 		assert.EqualError(t, err, "snapraid diff failed: exit status 1\nstderr:\n")
 	})
 
+	t.Run("Approve blocked run", func(t *testing.T) {
+		t.Parallel()
+
+		dummyConf := testutils.WriteFile(t, "# dummy snapraid config")
+		binPath := testutils.WriteScriptFile(t, "exit 1", 1)
+		outDir := t.TempDir()
+		result := `{"timestamp":"2025-06-01T03:00:00Z","result":{"removed":["a.txt"]},"error_kind":"threshold","fingerprint":"abc"}`
+		assert.NoError(t, os.WriteFile(filepath.Join(outDir, "2025-06-01T03:00:00Z.json"), []byte(result), 0o600))
+
+		cfgPath := testutils.WriteFile(t, fmt.Sprintf("snapraid_bin: %q\nsnapraid_config: %q\noutput_dir: %q", binPath, dummyConf, outDir))
+
+		var stdout bytes.Buffer
+		err := Run(context.Background(), "vTEST", "commit123", []string{"--config", cfgPath, "approve", "2025-06-01T03:00:00Z"}, &stdout)
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "Run approved")
+		assert.FileExists(t, filepath.Join(outDir, ".approval"))
+	})
+
+	t.Run("Approve without output dir", func(t *testing.T) {
+		t.Parallel()
+
+		dummyConf := testutils.WriteFile(t, "# dummy snapraid config")
+		binPath := testutils.WriteScriptFile(t, "exit 1", 1)
+		cfgPath := testutils.WriteFile(t, fmt.Sprintf("snapraid_bin: %q\nsnapraid_config: %q", binPath, dummyConf))
+
+		var stdout bytes.Buffer
+		err := Run(context.Background(), "vTEST", "commit123", []string{"--config", cfgPath, "approve", "2025-06-01T03:00:00Z"}, &stdout)
+		assert.EqualError(t, err, "output_dir must be set to approve a run")
+	})
+
 	t.Run("Disabled notification", func(t *testing.T) {
 		t.Parallel()
 
@@ -151,3 +207,38 @@ notifications:
 		assert.EqualError(t, err, "snapraid config file not found: /etc/snapraid-runner.yml")
 	})
 }
+
+func TestThresholds(t *testing.T) {
+	t.Parallel()
+
+	global := config.Thresholds{
+		Add:     &config.Threshold{Count: -1},
+		Remove:  &config.Threshold{Count: 80, Percent: 0.5},
+		Update:  &config.Threshold{Count: 400},
+		Copy:    &config.Threshold{Count: -1},
+		Move:    &config.Threshold{Count: -1},
+		Restore: &config.Threshold{Count: -1},
+	}
+	rules := []config.ThresholdRule{
+		{Path: "photos/**", Thresholds: config.Thresholds{Remove: &config.Threshold{Count: 0}}},
+		{Path: "downloads/**", Unlimited: true},
+	}
+
+	got := thresholds(global, rules, []string{".DS_Store"})
+
+	assert.Equal(t, 80, got.Remove)
+	assert.Equal(t, 0.5, got.RemovePercent)
+	assert.Equal(t, 400, got.Update)
+	assert.Equal(t, []snapraid.ThresholdRule{
+		{
+			Pattern: "photos/**",
+			Limits:  snapraid.Thresholds{Add: -1, Remove: 0, Update: -1, Move: -1, Copy: -1, Restore: -1},
+		},
+		{
+			Pattern:   "downloads/**",
+			Unlimited: true,
+			Limits:    snapraid.Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+		},
+	}, got.Rules)
+	assert.Equal(t, []string{".DS_Store"}, got.Ignore)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SnapraidCommands are the snapraid commands go-snapraid runs and accepts extra arguments for.
+var SnapraidCommands = []string{"touch", "diff", "sync", "scrub", "smart", "status", "check", "fix"}
+
+// managedOptions are set by go-snapraid itself and cannot be passed as extra arguments.
+var managedOptions = []string{
+	"-c", "--conf",
+	"-l", "--log",
+	"-q", "--quiet",
+	"-p", "--plan",
+	"-o", "--older-than",
+	"-d", "--filter-disk",
+	"-f", "--filter",
+	"-e", "--filter-error",
+}
+
+// safeOptions are the extra arguments allowed without allow_dangerous, mapped to whether
+// they take a value. Everything else requires allow_dangerous, including the force options
+// ("--force-zero", "--force-empty", "--force-full"), which would bypass force.zero_size and
+// force.empty_disks.
+var safeOptions = map[string]bool{
+	"-v": false, "--verbose": false,
+	"-h": false, "--pre-hash": false,
+	"-a": false, "--audit-only": false,
+	"-m": false, "--filter-missing": false,
+	"-i": true, "--import": true,
+	"-S": true, "--start": true,
+	"-B": true, "--count": true,
+	"--test-io-cache": true,
+}
+
+// ValidateExtraArgs checks extra arguments for a snapraid command. Options managed by
+// go-snapraid are always rejected; options outside the allowlist require allowDangerous.
+// A value is passed either inline ("--test-io-cache=8") or as the next argument.
+func ValidateExtraArgs(args []string, allowDangerous bool) error {
+	for i := 0; i < len(args); i++ {
+		name, _, inline := strings.Cut(args[i], "=")
+		if slices.Contains(managedOptions, name) {
+			return fmt.Errorf("%s is set by go-snapraid", name)
+		}
+
+		takesValue, safe := safeOptions[name]
+		switch {
+		case safe && takesValue && !inline:
+			if i+1 == len(args) {
+				return fmt.Errorf("%s requires a value", name)
+			}
+			i++
+		case safe && !takesValue && inline:
+			return fmt.Errorf("%s does not take a value", name)
+		case !safe && !allowDangerous:
+			return fmt.Errorf("%s is not allowlisted, set snapraid.allow_dangerous to pass it", args[i])
+		}
+	}
+	return nil
+}
+
+// validate checks that extra arguments are only given for known commands and pass the allowlist.
+func (s SnapraidOptions) validate() error {
+	for _, cmd := range slices.Sorted(maps.Keys(s.ExtraArgs)) {
+		if !slices.Contains(SnapraidCommands, cmd) {
+			return fmt.Errorf("snapraid.extra_args: unknown command %q", cmd)
+		}
+		if err := ValidateExtraArgs(s.ExtraArgs[cmd], s.AllowDangerous); err != nil {
+			return fmt.Errorf("snapraid.extra_args.%s: %w", cmd, err)
+		}
+	}
+	return nil
+}
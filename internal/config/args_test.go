@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Allowlisted options", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"-h", "--audit-only", "--test-io-cache", "8", "--start=100", "-v"}, false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Managed option", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"--plan=10"}, true)
+		assert.EqualError(t, err, "--plan is set by go-snapraid")
+	})
+
+	t.Run("Dangerous option", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"--force-realloc"}, false)
+		assert.EqualError(t, err, "--force-realloc is not allowlisted, set snapraid.allow_dangerous to pass it")
+
+		assert.NoError(t, ValidateExtraArgs([]string{"--force-realloc"}, true))
+	})
+
+	t.Run("Force options are dangerous", func(t *testing.T) {
+		t.Parallel()
+
+		for _, opt := range []string{"-Z", "--force-zero", "-E", "--force-empty", "-F", "--force-full"} {
+			err := ValidateExtraArgs([]string{opt}, false)
+			assert.EqualError(t, err, opt+" is not allowlisted, set snapraid.allow_dangerous to pass it")
+			assert.NoError(t, ValidateExtraArgs([]string{opt}, true))
+		}
+	})
+
+	t.Run("Missing value", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"--import"}, false)
+		assert.EqualError(t, err, "--import requires a value")
+	})
+
+	t.Run("Unexpected value", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"--pre-hash=1"}, false)
+		assert.EqualError(t, err, "--pre-hash does not take a value")
+	})
+
+	t.Run("Stray value", func(t *testing.T) {
+		t.Parallel()
+
+		err := ValidateExtraArgs([]string{"-v", "8"}, false)
+		assert.EqualError(t, err, "8 is not allowlisted, set snapraid.allow_dangerous to pass it")
+	})
+}
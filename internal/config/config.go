@@ -1,14 +1,30 @@
 package config
 
+import "time"
+
 // Config is the root structure for the YAML config file.
 type Config struct {
-	SnapraidBin    string       `yaml:"snapraid_bin"`    // SnapraidBin is the path to the snapraid executable (e.g., /usr/bin/snapraid).
-	SnapraidConfig string       `yaml:"snapraid_config"` // SnapraidConfig is the path to the snapraid configuration file used by the snapraid command.
-	OutputDir      string       `yaml:"output_dir"`      // OutputDir is the directory where JSON result files will be written. Leave empty to disable.
-	Thresholds     Thresholds   `yaml:"thresholds"`      // Thresholds defines numeric limits for file-change categories before blocking sync.
-	Steps          Steps        `yaml:"steps"`           // Steps toggles which SnapRAID subcommands to run (touch, scrub, smart).
-	Scrub          ScrubOptions `yaml:"scrub"`           // Scrub holds options for the "scrub" command (plan percentage and file age threshold).
-	Notify         Notify       `yaml:"notifications"`   // Notify contains Slack notification settings (token and channel).
+	SnapraidBin         string          `yaml:"snapraid_bin"`          // SnapraidBin is the path to the snapraid executable (e.g., /usr/bin/snapraid).
+	SnapraidConfig      string          `yaml:"snapraid_config"`       // SnapraidConfig is the path to the snapraid configuration file used by the snapraid command.
+	OutputDir           string          `yaml:"output_dir"`            // OutputDir is the directory where JSON result files will be written. Leave empty to disable.
+	GracePeriod         *time.Duration  `yaml:"grace_period"`          // GracePeriod is how long an interrupted snapraid child may take to exit before it is killed.
+	ProgressEvery       *time.Duration  `yaml:"progress_every"`        // ProgressEvery is the minimum interval between two logged sync/scrub progress lines.
+	Thresholds          Thresholds      `yaml:"thresholds"`            // Thresholds defines numeric limits for file-change categories before blocking sync.
+	ThresholdRules      []ThresholdRule `yaml:"threshold_rules"`       // ThresholdRules apply their own limits to matching paths, evaluated in order.
+	IgnoreForThresholds []string        `yaml:"ignore_for_thresholds"` // IgnoreForThresholds lists globs of paths that never count against thresholds.
+	Steps               Steps           `yaml:"steps"`                 // Steps toggles which SnapRAID subcommands to run (touch, scrub, smart, status).
+	Timeouts            Timeouts        `yaml:"timeouts"`              // Timeouts limits how long each step and the whole run may take.
+	Retry               Retries         `yaml:"retry"`                 // Retry defines per-step retry policies for transient failures.
+	Scrub               ScrubOptions    `yaml:"scrub"`                 // Scrub holds options for the "scrub" command (plan percentage and file age threshold).
+	Smart               SmartOptions    `yaml:"smart"`                 // Smart holds the limits used to highlight unhealthy disks in the smart report.
+	Approval            ApprovalOptions `yaml:"approval"`              // Approval controls how an approved blocked run releases the next sync.
+	Diff                DiffOptions     `yaml:"diff"`                  // Diff bounds the memory used for the parsed diff.
+	Settle              SettleOptions   `yaml:"settle"`                // Settle repeats diff until the changes stop changing before sync.
+	Repair              RepairOptions   `yaml:"repair"`                // Repair controls the handling of blocks with errors found by scrub or status.
+	Filters             Filters         `yaml:"filters"`               // Filters restrict diff, check and fix to some data disks or paths.
+	Snapraid            SnapraidOptions `yaml:"snapraid"`              // Snapraid controls how the snapraid binary is invoked.
+	Force               ForceOptions    `yaml:"force"`                 // Force allowlists zero-size files and empty disks a refused sync is rerun for.
+	Notify              Notify          `yaml:"notifications"`         // Notify contains Slack notification settings (token and channel).
 }
 
 // WantsSlackNotification returns true if Slack notifications
@@ -19,27 +35,130 @@ func (c Config) WantsSlackNotification(noNotify bool) bool {
 		c.Notify.SlackToken != ""
 }
 
-// Thresholds define numeric limits before sync is blocked.
+// Thresholds define absolute or relative limits before sync is blocked.
 type Thresholds struct {
-	Add     *int `yaml:"add"`     // Add is the maximum number of added files allowed before aborting sync. Set to –1 to disable.
-	Remove  *int `yaml:"remove"`  // Remove is the maximum number of removed files allowed before aborting sync. Set to –1 to disable.
-	Update  *int `yaml:"update"`  // Update is the maximum number of updated files allowed before aborting sync. Set to –1 to disable.
-	Copy    *int `yaml:"copy"`    // Copy is the maximum number of copied files allowed before aborting sync. Set to –1 to disable.
-	Move    *int `yaml:"move"`    // Move is the maximum number of moved files allowed before aborting sync. Set to –1 to disable.
-	Restore *int `yaml:"restore"` // Restore is the maximum number of restored files allowed before aborting sync. Set to –1 to disable.
+	Add     *Threshold `yaml:"add"`     // Add limits added files before aborting sync. Set to –1 to disable.
+	Remove  *Threshold `yaml:"remove"`  // Remove limits removed files before aborting sync. Set to –1 to disable.
+	Update  *Threshold `yaml:"update"`  // Update limits updated files before aborting sync. Set to –1 to disable.
+	Copy    *Threshold `yaml:"copy"`    // Copy limits copied files before aborting sync. Set to –1 to disable.
+	Move    *Threshold `yaml:"move"`    // Move limits moved files before aborting sync. Set to –1 to disable.
+	Restore *Threshold `yaml:"restore"` // Restore limits restored files before aborting sync. Set to –1 to disable.
+}
+
+// ThresholdRule applies its own limits to diff entries whose path matches Path.
+// The first matching rule claims an entry; categories the rule does not set are unlimited for it.
+type ThresholdRule struct {
+	Path       string           `yaml:"path"`      // Path is a glob relative to the disk root, e.g. "photos/**".
+	Unlimited  bool             `yaml:"unlimited"` // Unlimited exempts matching entries from every limit.
+	Thresholds `yaml:",inline"` // Thresholds are the limits for matching entries.
 }
 
 // Steps define which SnapRAID subcommands to run.
 type Steps struct {
-	Touch *bool `yaml:"touch"` // Touch enables the "snapraid touch" step before sync.
-	Scrub *bool `yaml:"scrub"` // Scrub enables the "snapraid scrub" step after sync.
-	Smart *bool `yaml:"smart"` // Smart enables the "snapraid smart" step after scrub.
+	Touch  *bool `yaml:"touch"`  // Touch enables the "snapraid touch" step before sync.
+	Scrub  *bool `yaml:"scrub"`  // Scrub enables the "snapraid scrub" step after sync.
+	Smart  *bool `yaml:"smart"`  // Smart enables the "snapraid smart" step after scrub.
+	Status *bool `yaml:"status"` // Status enables the "snapraid status" step after smart.
+	Verify *bool `yaml:"verify"` // Verify enables a "snapraid diff" after sync that expects no changes.
+}
+
+// Timeouts define time limits per step and for the whole run. Zero (the default) disables a limit.
+type Timeouts struct {
+	Touch  time.Duration `yaml:"touch"`  // Touch limits the "snapraid touch" step.
+	Diff   time.Duration `yaml:"diff"`   // Diff limits the "snapraid diff" step.
+	Sync   time.Duration `yaml:"sync"`   // Sync limits the "snapraid sync" step.
+	Scrub  time.Duration `yaml:"scrub"`  // Scrub limits the "snapraid scrub" step.
+	Smart  time.Duration `yaml:"smart"`  // Smart limits the "snapraid smart" step.
+	Status time.Duration `yaml:"status"` // Status limits the "snapraid status" step.
+	Repair time.Duration `yaml:"repair"` // Repair limits each phase of a repair: check, fix and the rescrub.
+	Total  time.Duration `yaml:"total"`  // Total is the budget for the whole run; scrub and smart are skipped if their limit no longer fits.
+}
+
+// Retries define a retry policy per step. Steps without a policy run once.
+type Retries struct {
+	Touch  RetryPolicy `yaml:"touch"`  // Touch is the retry policy for "snapraid touch".
+	Diff   RetryPolicy `yaml:"diff"`   // Diff is the retry policy for "snapraid diff".
+	Sync   RetryPolicy `yaml:"sync"`   // Sync is the retry policy for "snapraid sync".
+	Scrub  RetryPolicy `yaml:"scrub"`  // Scrub is the retry policy for "snapraid scrub".
+	Smart  RetryPolicy `yaml:"smart"`  // Smart is the retry policy for "snapraid smart".
+	Status RetryPolicy `yaml:"status"` // Status is the retry policy for "snapraid status".
+	Repair RetryPolicy `yaml:"repair"` // Repair is the retry policy for each phase of a repair: check, fix and the rescrub.
+}
+
+// RetryPolicy defines when and how often a failed step is retried.
+type RetryPolicy struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // MaxAttempts is the total number of attempts including the first. 0 or 1 disables retries.
+	Backoff        time.Duration `yaml:"backoff"`         // Backoff is the wait before the first retry; it doubles after every further attempt.
+	ExitCodes      []int         `yaml:"exit_codes"`      // ExitCodes lists exit codes that are retried. snapraid exits with 1 for most failures, including data errors.
+	StderrPatterns []string      `yaml:"stderr_patterns"` // StderrPatterns are regular expressions matched against stderr to decide if a failure is retried.
+	// Without ExitCodes or StderrPatterns nothing is retried.
 }
 
 // ScrubOptions control the `scrub` command.
 type ScrubOptions struct {
-	Plan      *int `yaml:"plan"`       // Plan is the percentage (0–100) used by "snapraid scrub".
-	OlderThan *int `yaml:"older_than"` // OlderThan is the minimum file age in days for "snapraid scrub" to include.
+	Plan      *string `yaml:"plan"`       // Plan is the percentage (0–100) or keyword ("new", "bad", "full") used by "snapraid scrub".
+	OlderThan *int    `yaml:"older_than"` // OlderThan is the minimum file age in days for "snapraid scrub" to include. Only used with a percentage plan.
+
+	TargetCoverageDays int           `yaml:"target_coverage_days"` // TargetCoverageDays, if set, replaces Plan and OlderThan with a plan that scrubs every block within this many days.
+	MaxDuration        time.Duration `yaml:"max_duration"`         // MaxDuration caps the scrub duration the planner estimates from past runs. 0 uses timeouts.scrub.
+}
+
+// SmartOptions define when a disk in the smart report is highlighted.
+type SmartOptions struct {
+	MaxFailureProbability *int `yaml:"max_failure_probability"` // MaxFailureProbability is the highest acceptable failure probability in percent. Set to –1 to disable.
+	MaxTemperature        *int `yaml:"max_temperature"`         // MaxTemperature is the highest acceptable disk temperature in °C. Set to –1 to disable.
+}
+
+// ApprovalOptions control the approval of runs blocked by the threshold gate.
+type ApprovalOptions struct {
+	Tolerance *int `yaml:"tolerance"` // Tolerance is how many diff entries may differ from the approved run. 0 requires an exact match.
+}
+
+// DiffOptions bound the memory used for the parsed diff.
+type DiffOptions struct {
+	MaxPaths *int   `yaml:"max_paths"` // MaxPaths is the maximum number of paths kept per change category. 0 keeps all.
+	SpillDir string `yaml:"spill_dir"` // SpillDir is a directory receiving a file with every changed path. Leave empty to disable.
+}
+
+// SettleOptions control the quiescence check before sync.
+type SettleOptions struct {
+	Interval time.Duration `yaml:"interval"` // Interval is the wait between two diffs. 0 (the default) disables the check.
+	Attempts *int          `yaml:"attempts"` // Attempts is how many repeated diffs are compared before sync is deferred.
+}
+
+// RepairOptions control the automatic repair of blocks with errors.
+type RepairOptions struct {
+	Policy string `yaml:"policy"` // Policy is "off" (the default), "check" to only check the bad blocks or "fix" to repair them.
+}
+
+// Filters restrict snapraid commands to some data disks or paths. snapraid only accepts
+// filters for check and fix, so the diff filter is applied to the diff output instead;
+// sync and scrub always process the whole array.
+type Filters struct {
+	Diff  Filter `yaml:"diff"`  // Diff narrows the reported diff. A run with a diff filter never syncs.
+	Check Filter `yaml:"check"` // Check filters "snapraid check" run by the repair.
+	Fix   Filter `yaml:"fix"`   // Fix filters "snapraid fix" run by the repair.
+}
+
+// Filter selects the data disks and paths a snapraid command processes.
+type Filter struct {
+	Disks        []string `yaml:"disks"`         // Disks lists the only data disks to process. Mutually exclusive with ExcludeDisks.
+	ExcludeDisks []string `yaml:"exclude_disks"` // ExcludeDisks lists data disks to skip; every other data disk is processed.
+	Paths        []string `yaml:"paths"`         // Paths lists patterns of the only files to process, as accepted by "snapraid --filter".
+}
+
+// SnapraidOptions control how the snapraid binary is invoked.
+type SnapraidOptions struct {
+	Quiet          *bool               `yaml:"quiet"`           // Quiet passes "--quiet" to every command except sync, scrub, check and fix. Defaults to true.
+	AllowDangerous bool                `yaml:"allow_dangerous"` // AllowDangerous permits extra arguments outside the allowlist, e.g. "--force-realloc".
+	ExtraArgs      map[string][]string `yaml:"extra_args"`      // ExtraArgs are passed to the named snapraid command, e.g. "sync": ["--pre-hash"].
+}
+
+// ForceOptions allowlist the files and disks for which a refused sync is rerun with
+// "--force-zero" or "--force-empty". Empty lists never force.
+type ForceOptions struct {
+	ZeroSize   []string `yaml:"zero_size"`   // ZeroSize lists globs of files, relative to their disk, that may be synced with zero size.
+	EmptyDisks []string `yaml:"empty_disks"` // EmptyDisks lists data disks that may be synced while missing all their files.
 }
 
 // Notify defines Slack notification options.
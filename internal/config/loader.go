@@ -3,20 +3,30 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gi8lino/go-snapraid/internal/utils"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	defaultAddThreshold     = -1  // no limit on added files
-	defaultRemoveThreshold  = 80  // default max removed files
-	defaultUpdateThreshold  = 400 // default max updated files
-	defaultCopyThreshold    = -1  // no limit on copied files
-	defaultMoveThreshold    = -1  // no limit on moved files
-	defaultRestoreThreshold = -1  // no limit on restored files
-	defaultScrubPlan        = 22  // default scrub plan percentage
-	defaultScrubOlderThan   = 12  // default scrub older‐than days
+	defaultAddThreshold     = -1     // no limit on added files
+	defaultRemoveThreshold  = 80     // default max removed files
+	defaultUpdateThreshold  = 400    // default max updated files
+	defaultCopyThreshold    = -1     // no limit on copied files
+	defaultMoveThreshold    = -1     // no limit on moved files
+	defaultRestoreThreshold = -1     // no limit on restored files
+	defaultScrubPlan        = "22"   // default scrub plan percentage
+	defaultScrubOlderThan   = 12     // default scrub older‐than days
+	defaultMaxFailureProb   = 50     // default max failure probability in percent
+	defaultMaxTemperature   = 50     // default max disk temperature in °C
+	defaultApprovalTol      = 0      // approved diffs must match exactly
+	defaultDiffMaxPaths     = 100000 // default max paths kept per diff category
+	defaultSettleAttempts   = 3      // default repeated diffs before sync is deferred
+	defaultRepairPolicy     = "off"  // errors are only reported by default
+
+	defaultGracePeriod   = 60 * time.Second // default time snapraid gets to exit after SIGINT
+	defaultProgressEvery = 30 * time.Second // default interval between logged progress lines
 )
 
 // LoadConfig reads the given file, parses it into a Config struct, applies defaults, and returns it.
@@ -38,22 +48,22 @@ func LoadConfig(path string) (Config, error) {
 func (c *Config) ApplyDefaults() {
 	// Thresholds: if pointer is nil → assign default; if non‐nil, leave as-is.
 	if c.Thresholds.Add == nil {
-		c.Thresholds.Add = utils.Ptr(defaultAddThreshold)
+		c.Thresholds.Add = &Threshold{Count: defaultAddThreshold}
 	}
 	if c.Thresholds.Remove == nil {
-		c.Thresholds.Remove = utils.Ptr(defaultRemoveThreshold)
+		c.Thresholds.Remove = &Threshold{Count: defaultRemoveThreshold}
 	}
 	if c.Thresholds.Update == nil {
-		c.Thresholds.Update = utils.Ptr(defaultUpdateThreshold)
+		c.Thresholds.Update = &Threshold{Count: defaultUpdateThreshold}
 	}
 	if c.Thresholds.Copy == nil {
-		c.Thresholds.Copy = utils.Ptr(defaultCopyThreshold)
+		c.Thresholds.Copy = &Threshold{Count: defaultCopyThreshold}
 	}
 	if c.Thresholds.Move == nil {
-		c.Thresholds.Move = utils.Ptr(defaultMoveThreshold)
+		c.Thresholds.Move = &Threshold{Count: defaultMoveThreshold}
 	}
 	if c.Thresholds.Restore == nil {
-		c.Thresholds.Restore = utils.Ptr(defaultRestoreThreshold)
+		c.Thresholds.Restore = &Threshold{Count: defaultRestoreThreshold}
 	}
 
 	// ScrubOptions: if pointer is nil → assign default; otherwise honor user value.
@@ -64,6 +74,49 @@ func (c *Config) ApplyDefaults() {
 		c.Scrub.OlderThan = utils.Ptr(defaultScrubOlderThan)
 	}
 
+	// SmartOptions: if pointer is nil → assign default; otherwise honor user value.
+	if c.Smart.MaxFailureProbability == nil {
+		c.Smart.MaxFailureProbability = utils.Ptr(defaultMaxFailureProb)
+	}
+	if c.Smart.MaxTemperature == nil {
+		c.Smart.MaxTemperature = utils.Ptr(defaultMaxTemperature)
+	}
+
+	// ApprovalOptions: if pointer is nil → assign default; otherwise honor user value.
+	if c.Approval.Tolerance == nil {
+		c.Approval.Tolerance = utils.Ptr(defaultApprovalTol)
+	}
+
+	// DiffOptions: if pointer is nil → assign default; otherwise honor user value.
+	if c.Diff.MaxPaths == nil {
+		c.Diff.MaxPaths = utils.Ptr(defaultDiffMaxPaths)
+	}
+
+	// SettleOptions: if pointer is nil → assign default; otherwise honor user value.
+	if c.Settle.Attempts == nil {
+		c.Settle.Attempts = utils.Ptr(defaultSettleAttempts)
+	}
+
+	// RepairOptions: if empty → assign default; otherwise honor user value.
+	if c.Repair.Policy == "" {
+		c.Repair.Policy = defaultRepairPolicy
+	}
+
+	// SnapraidOptions: if pointer is nil → assign default; otherwise honor user value.
+	if c.Snapraid.Quiet == nil {
+		c.Snapraid.Quiet = utils.Ptr(true)
+	}
+
+	// GracePeriod: if pointer is nil → assign default; otherwise honor user value.
+	if c.GracePeriod == nil {
+		c.GracePeriod = utils.Ptr(defaultGracePeriod)
+	}
+
+	// ProgressEvery: if pointer is nil → assign default; otherwise honor user value.
+	if c.ProgressEvery == nil {
+		c.ProgressEvery = utils.Ptr(defaultProgressEvery)
+	}
+
 	// Steps: if pointer is nil → assign default false; otherwise honor user value.
 	if c.Steps.Touch == nil {
 		c.Steps.Touch = utils.Ptr(false)
@@ -74,4 +127,10 @@ func (c *Config) ApplyDefaults() {
 	if c.Steps.Smart == nil {
 		c.Steps.Smart = utils.Ptr(false)
 	}
+	if c.Steps.Status == nil {
+		c.Steps.Status = utils.Ptr(false)
+	}
+	if c.Steps.Verify == nil {
+		c.Steps.Verify = utils.Ptr(false)
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gi8lino/go-snapraid/internal/utils"
 	"github.com/stretchr/testify/assert"
@@ -61,10 +62,67 @@ steps:
   touch: true
   scrub: false
   smart: true
+  status: true
+  verify: true
 
 scrub:
   plan: 5
   older_than: 7
+  target_coverage_days: 30
+  max_duration: 3h
+
+smart:
+  max_failure_probability: 30
+  max_temperature: 45
+
+approval:
+  tolerance: 3
+
+diff:
+  max_paths: 500
+  spill_dir: /var/tmp
+
+settle:
+  interval: 10m
+  attempts: 5
+
+repair:
+  policy: fix
+
+filters:
+  diff:
+    paths: ["photos/"]
+  check:
+    disks: [d2]
+  fix:
+    exclude_disks: [d3]
+    paths: ["movies/*"]
+
+snapraid:
+  quiet: false
+  allow_dangerous: true
+  extra_args:
+    sync: ["--pre-hash", "--force-realloc"]
+
+force:
+  zero_size: ["*.lock"]
+  empty_disks: [d3]
+
+ignore_for_thresholds: [".DS_Store", "*.nfo"]
+
+grace_period: 2m
+
+timeouts:
+  sync: 6h
+  scrub: 90m
+  total: 8h
+
+retry:
+  sync:
+    max_attempts: 3
+    backoff: 5m
+    exit_codes: [1]
+    stderr_patterns: ["locked"]
 
 notifications:
   slack_token: "xoxb-123"
@@ -83,26 +141,75 @@ notifications:
 
 		// Verify thresholds
 		expThresh := Thresholds{
-			Add:     utils.Ptr(10),
-			Remove:  utils.Ptr(20),
-			Update:  utils.Ptr(30),
-			Copy:    utils.Ptr(40),
-			Move:    utils.Ptr(50),
-			Restore: utils.Ptr(60),
+			Add:     &Threshold{Count: 10},
+			Remove:  &Threshold{Count: 20},
+			Update:  &Threshold{Count: 30},
+			Copy:    &Threshold{Count: 40},
+			Move:    &Threshold{Count: 50},
+			Restore: &Threshold{Count: 60},
 		}
 		assert.Equal(t, expThresh, cfg.Thresholds)
 
 		// Verify steps
 		expSteps := Steps{
-			Touch: utils.Ptr(true),
-			Scrub: utils.Ptr(false),
-			Smart: utils.Ptr(true),
+			Touch:  utils.Ptr(true),
+			Scrub:  utils.Ptr(false),
+			Smart:  utils.Ptr(true),
+			Status: utils.Ptr(true),
+			Verify: utils.Ptr(true),
 		}
 		assert.Equal(t, expSteps, cfg.Steps)
 
 		// Verify scrub options
-		assert.Equal(t, 5, *cfg.Scrub.Plan)
+		assert.Equal(t, "5", *cfg.Scrub.Plan)
 		assert.Equal(t, 7, *cfg.Scrub.OlderThan)
+		assert.Equal(t, 30, cfg.Scrub.TargetCoverageDays)
+		assert.Equal(t, 3*time.Hour, cfg.Scrub.MaxDuration)
+
+		// Verify smart limits
+		assert.Equal(t, 30, *cfg.Smart.MaxFailureProbability)
+		assert.Equal(t, 45, *cfg.Smart.MaxTemperature)
+
+		// Verify approval options
+		assert.Equal(t, 3, *cfg.Approval.Tolerance)
+
+		// Verify diff options
+		assert.Equal(t, 500, *cfg.Diff.MaxPaths)
+		assert.Equal(t, "/var/tmp", cfg.Diff.SpillDir)
+
+		// Verify settle options
+		assert.Equal(t, 10*time.Minute, cfg.Settle.Interval)
+		assert.Equal(t, 5, *cfg.Settle.Attempts)
+
+		// Verify repair policy
+		assert.Equal(t, "fix", cfg.Repair.Policy)
+
+		// Verify snapraid options
+		assert.False(t, *cfg.Snapraid.Quiet)
+		assert.True(t, cfg.Snapraid.AllowDangerous)
+		assert.Equal(t, map[string][]string{"sync": {"--pre-hash", "--force-realloc"}}, cfg.Snapraid.ExtraArgs)
+
+		// Verify force allowlists
+		assert.Equal(t, ForceOptions{ZeroSize: []string{"*.lock"}, EmptyDisks: []string{"d3"}}, cfg.Force)
+
+		// Verify filters
+		assert.Equal(t, Filter{Paths: []string{"photos/"}}, cfg.Filters.Diff)
+		assert.Equal(t, Filter{Disks: []string{"d2"}}, cfg.Filters.Check)
+		assert.Equal(t, Filter{ExcludeDisks: []string{"d3"}, Paths: []string{"movies/*"}}, cfg.Filters.Fix)
+
+		// Verify ignore globs
+		assert.Equal(t, []string{".DS_Store", "*.nfo"}, cfg.IgnoreForThresholds)
+
+		// Verify grace period
+		assert.Equal(t, 2*time.Minute, *cfg.GracePeriod)
+
+		// Verify timeouts
+		assert.Equal(t, Timeouts{Sync: 6 * time.Hour, Scrub: 90 * time.Minute, Total: 8 * time.Hour}, cfg.Timeouts)
+
+		// Verify retry policies
+		expRetry := RetryPolicy{MaxAttempts: 3, Backoff: 5 * time.Minute, ExitCodes: []int{1}, StderrPatterns: []string{"locked"}}
+		assert.Equal(t, expRetry, cfg.Retry.Sync)
+		assert.Equal(t, RetryPolicy{}, cfg.Retry.Scrub)
 
 		// Verify notifications
 		assert.Equal(t, "xoxb-123", cfg.Notify.SlackToken)
@@ -136,28 +243,70 @@ notifications:
 		assert.NoError(t, err)
 
 		// Verify thresholds use defaults when omitted
-		assert.Equal(t, -1, *cfg.Thresholds.Add)     // defaultAddThreshold
-		assert.Equal(t, 80, *cfg.Thresholds.Remove)  // defaultRemoveThreshold
-		assert.Equal(t, 400, *cfg.Thresholds.Update) // defaultUpdateThreshold
-		assert.Equal(t, -1, *cfg.Thresholds.Copy)    // defaultCopyThreshold
-		assert.Equal(t, -1, *cfg.Thresholds.Move)    // defaultMoveThreshold
-		assert.Equal(t, -1, *cfg.Thresholds.Restore) // defaultRestoreThreshold
+		assert.Equal(t, -1, cfg.Thresholds.Add.Count)     // defaultAddThreshold
+		assert.Equal(t, 80, cfg.Thresholds.Remove.Count)  // defaultRemoveThreshold
+		assert.Equal(t, 400, cfg.Thresholds.Update.Count) // defaultUpdateThreshold
+		assert.Equal(t, -1, cfg.Thresholds.Copy.Count)    // defaultCopyThreshold
+		assert.Equal(t, -1, cfg.Thresholds.Move.Count)    // defaultMoveThreshold
+		assert.Equal(t, -1, cfg.Thresholds.Restore.Count) // defaultRestoreThreshold
 
 		// Verify scrub options use defaults when omitted
-		assert.Equal(t, 22, *cfg.Scrub.Plan)      // defaultScrubPlan
+		assert.Equal(t, "22", *cfg.Scrub.Plan)    // defaultScrubPlan
 		assert.Equal(t, 12, *cfg.Scrub.OlderThan) // defaultScrubOlderThan
 
+		// Verify smart limits use defaults when omitted
+		assert.Equal(t, defaultMaxFailureProb, *cfg.Smart.MaxFailureProbability)
+		assert.Equal(t, defaultMaxTemperature, *cfg.Smart.MaxTemperature)
+
+		// Verify approval tolerance uses default when omitted
+		assert.Equal(t, defaultApprovalTol, *cfg.Approval.Tolerance)
+
+		// Verify diff path limit uses default when omitted
+		assert.Equal(t, defaultDiffMaxPaths, *cfg.Diff.MaxPaths)
+
+		// Verify settle check is disabled by default
+		assert.Zero(t, cfg.Settle.Interval)
+		assert.Equal(t, defaultSettleAttempts, *cfg.Settle.Attempts)
+
+		// Verify repair is off by default
+		assert.Equal(t, defaultRepairPolicy, cfg.Repair.Policy)
+
+		// Verify snapraid runs quietly without extra arguments by default
+		assert.True(t, *cfg.Snapraid.Quiet)
+		assert.Nil(t, cfg.Snapraid.ExtraArgs)
+		assert.Equal(t, ForceOptions{}, cfg.Force)
+
 		// Steps and notifications should be as provided
 		expSteps := Steps{
-			Touch: utils.Ptr(false),
-			Scrub: utils.Ptr(true),
-			Smart: utils.Ptr(false),
+			Touch:  utils.Ptr(false),
+			Scrub:  utils.Ptr(true),
+			Smart:  utils.Ptr(false),
+			Status: utils.Ptr(false),
+			Verify: utils.Ptr(false),
 		}
 		assert.Equal(t, expSteps, cfg.Steps)
 		assert.Equal(t, "token", cfg.Notify.SlackToken)
 		assert.Equal(t, "#channel", cfg.Notify.SlackChannel)
 	})
 
+	t.Run("Scrub plan keyword", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keyword.yml")
+		content := `
+snapraid_bin: "/usr/bin/snapraid"
+snapraid_config: "/etc/snapraid.conf"
+
+scrub:
+  plan: new
+`
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		cfg, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", *cfg.Scrub.Plan)
+	})
+
 	t.Run("Explicit zero values for thresholds and scrub should be honored", func(t *testing.T) {
 		t.Parallel()
 
@@ -198,12 +347,12 @@ notifications:
 
 		// Verify all thresholds are exactly zero (not defaulted)
 		expThresh := Thresholds{
-			Add:     utils.Ptr(0),
-			Remove:  utils.Ptr(0),
-			Update:  utils.Ptr(0),
-			Copy:    utils.Ptr(0),
-			Move:    utils.Ptr(0),
-			Restore: utils.Ptr(0),
+			Add:     &Threshold{Count: 0},
+			Remove:  &Threshold{Count: 0},
+			Update:  &Threshold{Count: 0},
+			Copy:    &Threshold{Count: 0},
+			Move:    &Threshold{Count: 0},
+			Restore: &Threshold{Count: 0},
 		}
 		assert.Equal(t, expThresh.Add, cfg.Thresholds.Add)
 		assert.Equal(t, expThresh.Remove, cfg.Thresholds.Remove)
@@ -213,7 +362,7 @@ notifications:
 		assert.Equal(t, expThresh.Restore, cfg.Thresholds.Restore)
 
 		// Verify scrub options are exactly zero (not defaulted)
-		assert.Equal(t, 0, *cfg.Scrub.Plan)
+		assert.Equal(t, "0", *cfg.Scrub.Plan)
 		assert.Equal(t, 0, *cfg.Scrub.OlderThan)
 	})
 }
@@ -242,13 +391,15 @@ notifications:
 		cfg.ApplyDefaults()
 		assert.NoError(t, err)
 
-		assert.Equal(t, defaultAddThreshold, *cfg.Thresholds.Add)
-		assert.Equal(t, defaultRemoveThreshold, *cfg.Thresholds.Remove)
-		assert.Equal(t, defaultUpdateThreshold, *cfg.Thresholds.Update)
-		assert.Equal(t, defaultCopyThreshold, *cfg.Thresholds.Copy)
-		assert.Equal(t, defaultMoveThreshold, *cfg.Thresholds.Move)
-		assert.Equal(t, defaultRestoreThreshold, *cfg.Thresholds.Restore)
+		assert.Equal(t, defaultAddThreshold, cfg.Thresholds.Add.Count)
+		assert.Equal(t, defaultRemoveThreshold, cfg.Thresholds.Remove.Count)
+		assert.Equal(t, defaultUpdateThreshold, cfg.Thresholds.Update.Count)
+		assert.Equal(t, defaultCopyThreshold, cfg.Thresholds.Copy.Count)
+		assert.Equal(t, defaultMoveThreshold, cfg.Thresholds.Move.Count)
+		assert.Equal(t, defaultRestoreThreshold, cfg.Thresholds.Restore.Count)
 		assert.Equal(t, defaultScrubPlan, *cfg.Scrub.Plan)
 		assert.Equal(t, defaultScrubOlderThan, *cfg.Scrub.OlderThan)
+		assert.Equal(t, defaultGracePeriod, *cfg.GracePeriod)
+		assert.Equal(t, defaultProgressEvery, *cfg.ProgressEvery)
 	})
 }
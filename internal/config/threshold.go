@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Threshold is the limit for one file-change category. In YAML it is either an absolute
+// count (`remove: 80`), a percentage of the array (`remove: "0.5%"`) or a list combining
+// both (`remove: [80, "0.5%"]`). With both set, exceeding either limit blocks sync.
+type Threshold struct {
+	Count   int     // Count is the maximum number of changed files. Set to –1 to disable.
+	Percent float64 // Percent is the maximum share of the array in percent. 0 disables.
+}
+
+// UnmarshalYAML accepts a scalar count or percentage, or a list of both.
+func (t *Threshold) UnmarshalYAML(node *yaml.Node) error {
+	*t = Threshold{Count: -1}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return t.set(node.Value)
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: threshold list entries must be a count or a percentage", item.Line)
+			}
+			if err := t.set(item.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: threshold must be a count, a percentage or a list of both", node.Line)
+	}
+}
+
+// set parses a single count ("80") or percentage ("0.5%") into t.
+func (t *Threshold) set(value string) error {
+	value = strings.TrimSpace(value)
+
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil {
+			return fmt.Errorf("invalid threshold percentage %q", value)
+		}
+		t.Percent = p
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid threshold %q: must be a count or a percentage such as \"0.5%%\"", value)
+	}
+	t.Count = n
+	return nil
+}
+
+// validate checks that the count and percentage are in range.
+func (t Threshold) validate(name string) error {
+	if t.Count < -1 {
+		return fmt.Errorf("thresholds.%s must be >= -1", name)
+	}
+	if t.Percent < 0 || t.Percent > 100 {
+		return fmt.Errorf("thresholds.%s percentage must be between 0–100", name)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestThresholdUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		yaml string
+		want Threshold
+	}{
+		{"Count", "remove: 80", Threshold{Count: 80}},
+		{"Disabled", "remove: -1", Threshold{Count: -1}},
+		{"Percentage", `remove: "0.5%"`, Threshold{Count: -1, Percent: 0.5}},
+		{"Combined", `remove: [80, "0.5%"]`, Threshold{Count: 80, Percent: 0.5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var th Thresholds
+			assert.NoError(t, yaml.Unmarshal([]byte(tc.yaml), &th))
+			assert.Equal(t, tc.want, *th.Remove)
+		})
+	}
+
+	t.Run("Invalid value", func(t *testing.T) {
+		t.Parallel()
+
+		var th Thresholds
+		err := yaml.Unmarshal([]byte("remove: lots"), &th)
+		assert.EqualError(t, err, `invalid threshold "lots": must be a count or a percentage such as "0.5%"`)
+	})
+
+	t.Run("Invalid percentage", func(t *testing.T) {
+		t.Parallel()
+
+		var th Thresholds
+		err := yaml.Unmarshal([]byte(`remove: "x%"`), &th)
+		assert.EqualError(t, err, `invalid threshold percentage "x%"`)
+	})
+
+	t.Run("Mapping is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		var th Thresholds
+		err := yaml.Unmarshal([]byte("remove:\n  count: 1"), &th)
+		assert.EqualError(t, err, "line 2: threshold must be a count, a percentage or a list of both")
+	})
+}
+
+func TestThresholdValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, Threshold{Count: 10, Percent: 0.5}.validate("remove"))
+	})
+
+	t.Run("Count below -1", func(t *testing.T) {
+		t.Parallel()
+		assert.EqualError(t, Threshold{Count: -2}.validate("remove"), "thresholds.remove must be >= -1")
+	})
+
+	t.Run("Percentage above 100", func(t *testing.T) {
+		t.Parallel()
+		assert.EqualError(t, Threshold{Count: -1, Percent: 120}.validate("add"), "thresholds.add percentage must be between 0–100")
+	})
+}
+
+func TestThresholdRules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parse ordered rules", func(t *testing.T) {
+		t.Parallel()
+
+		raw := `
+threshold_rules:
+  - path: "photos/**"
+    remove: 0
+    update: 5
+  - path: "downloads/**"
+    unlimited: true
+`
+		var cfg Config
+		assert.NoError(t, yaml.Unmarshal([]byte(raw), &cfg))
+		assert.Equal(t, []ThresholdRule{
+			{Path: "photos/**", Thresholds: Thresholds{Remove: &Threshold{Count: 0}, Update: &Threshold{Count: 5}}},
+			{Path: "downloads/**", Unlimited: true},
+		}, cfg.ThresholdRules)
+	})
+
+	t.Run("Missing path", func(t *testing.T) {
+		t.Parallel()
+		err := ThresholdRule{}.validate(0)
+		assert.EqualError(t, err, "threshold_rules[0].path must be set")
+	})
+
+	t.Run("Unlimited with limits", func(t *testing.T) {
+		t.Parallel()
+		rule := ThresholdRule{Path: "a/**", Unlimited: true, Thresholds: Thresholds{Add: &Threshold{Count: 1}}}
+		assert.EqualError(t, rule.validate(1), "threshold_rules[1] (a/**): unlimited rules must not set limits")
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		t.Parallel()
+		rule := ThresholdRule{Path: "a/**", Thresholds: Thresholds{Remove: &Threshold{Count: -5}}}
+		assert.EqualError(t, rule.validate(2), "threshold_rules[2] (a/**): thresholds.remove must be >= -1")
+	})
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Validate checks that required paths and scrub options exist or are sane.
@@ -26,8 +30,8 @@ func (c Config) Validate() error {
 	if c.Scrub.Plan == nil {
 		return fmt.Errorf("scrub.plan must be set")
 	}
-	if *c.Scrub.Plan < 0 || *c.Scrub.Plan > 100 {
-		return fmt.Errorf("scrub.plan must be between 0–100")
+	if err := ValidateScrubPlan(*c.Scrub.Plan); err != nil {
+		return fmt.Errorf("scrub.plan %w", err)
 	}
 
 	// After ApplyDefaults, c.Scrub.OlderThan is guaranteed non‐nil.
@@ -37,6 +41,248 @@ func (c Config) Validate() error {
 	if *c.Scrub.OlderThan < 0 {
 		return fmt.Errorf("scrub.older_than must be >= 0")
 	}
+	if c.Scrub.TargetCoverageDays < 0 {
+		return fmt.Errorf("scrub.target_coverage_days must be >= 0")
+	}
+	if c.Scrub.MaxDuration < 0 {
+		return fmt.Errorf("scrub.max_duration must be >= 0")
+	}
+
+	if p := c.Smart.MaxFailureProbability; p != nil && (*p < -1 || *p > 100) {
+		return fmt.Errorf("smart.max_failure_probability must be between 0–100 or -1")
+	}
+	if t := c.Smart.MaxTemperature; t != nil && *t < -1 {
+		return fmt.Errorf("smart.max_temperature must be >= 0 or -1")
+	}
+
+	if t := c.Approval.Tolerance; t != nil && *t < 0 {
+		return fmt.Errorf("approval.tolerance must be >= 0")
+	}
+
+	if m := c.Diff.MaxPaths; m != nil && *m < 0 {
+		return fmt.Errorf("diff.max_paths must be >= 0")
+	}
+	if dir := c.Diff.SpillDir; dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("diff.spill_dir not found: %s", dir)
+		}
+	}
+
+	if c.Settle.Interval < 0 {
+		return fmt.Errorf("settle.interval must be >= 0")
+	}
+	if a := c.Settle.Attempts; a != nil && *a < 1 {
+		return fmt.Errorf("settle.attempts must be >= 1")
+	}
+
+	switch c.Repair.Policy {
+	case "", "off", "check", "fix":
+	default:
+		return fmt.Errorf("repair.policy must be one of off, check, fix")
+	}
+
+	if err := c.Filters.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Snapraid.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Force.validate(); err != nil {
+		return err
+	}
+
+	if c.GracePeriod != nil && *c.GracePeriod < 0 {
+		return fmt.Errorf("grace_period must be >= 0")
+	}
+
+	if c.ProgressEvery != nil && *c.ProgressEvery < 0 {
+		return fmt.Errorf("progress_every must be >= 0")
+	}
+
+	if err := c.Thresholds.validate(); err != nil {
+		return err
+	}
 
+	for i, rule := range c.ThresholdRules {
+		if err := rule.validate(i); err != nil {
+			return err
+		}
+	}
+
+	for i, glob := range c.IgnoreForThresholds {
+		if strings.TrimSpace(glob) == "" {
+			return fmt.Errorf("ignore_for_thresholds[%d] must not be empty", i)
+		}
+	}
+
+	if err := c.Timeouts.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Retry.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateScrubPlan checks that plan is a percentage between 0 and 100 or one of the
+// keywords "new", "bad" and "full" accepted by "snapraid scrub --plan".
+func ValidateScrubPlan(plan string) error {
+	switch plan {
+	case "new", "bad", "full":
+		return nil
+	}
+	if n, err := strconv.Atoi(plan); err != nil || n < 0 || n > 100 {
+		return fmt.Errorf("must be between 0–100 or one of new, bad, full")
+	}
+	return nil
+}
+
+// validate checks every configured threshold.
+func (t Thresholds) validate() error {
+	thresholds := []struct {
+		name      string
+		threshold *Threshold
+	}{
+		{"add", t.Add},
+		{"remove", t.Remove},
+		{"update", t.Update},
+		{"copy", t.Copy},
+		{"move", t.Move},
+		{"restore", t.Restore},
+	}
+	for _, th := range thresholds {
+		if th.threshold == nil {
+			continue
+		}
+		if err := th.threshold.validate(th.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks that the rule has a path and that its limits are sane.
+func (r ThresholdRule) validate(index int) error {
+	if r.Path == "" {
+		return fmt.Errorf("threshold_rules[%d].path must be set", index)
+	}
+	if r.Unlimited && r.Thresholds != (Thresholds{}) {
+		return fmt.Errorf("threshold_rules[%d] (%s): unlimited rules must not set limits", index, r.Path)
+	}
+	if err := r.Thresholds.validate(); err != nil {
+		return fmt.Errorf("threshold_rules[%d] (%s): %w", index, r.Path, err)
+	}
+	return nil
+}
+
+// validate checks the filter of every command.
+func (f Filters) validate() error {
+	filters := []struct {
+		name   string
+		filter Filter
+	}{
+		{"diff", f.Diff},
+		{"check", f.Check},
+		{"fix", f.Fix},
+	}
+	for _, fl := range filters {
+		if len(fl.filter.Disks) > 0 && len(fl.filter.ExcludeDisks) > 0 {
+			return fmt.Errorf("filters.%s: disks and exclude_disks are mutually exclusive", fl.name)
+		}
+		lists := []struct {
+			name   string
+			values []string
+		}{
+			{"disks", fl.filter.Disks},
+			{"exclude_disks", fl.filter.ExcludeDisks},
+			{"paths", fl.filter.Paths},
+		}
+		for _, list := range lists {
+			for i, v := range list.values {
+				if strings.TrimSpace(v) == "" {
+					return fmt.Errorf("filters.%s.%s[%d] must not be empty", fl.name, list.name, i)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validate ensures no allowlist entry is empty.
+func (f ForceOptions) validate() error {
+	lists := []struct {
+		name   string
+		values []string
+	}{
+		{"zero_size", f.ZeroSize},
+		{"empty_disks", f.EmptyDisks},
+	}
+	for _, list := range lists {
+		for i, v := range list.values {
+			if strings.TrimSpace(v) == "" {
+				return fmt.Errorf("force.%s[%d] must not be empty", list.name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// validate ensures no timeout is negative.
+func (t Timeouts) validate() error {
+	limits := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"touch", t.Touch},
+		{"diff", t.Diff},
+		{"sync", t.Sync},
+		{"scrub", t.Scrub},
+		{"smart", t.Smart},
+		{"status", t.Status},
+		{"repair", t.Repair},
+		{"total", t.Total},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return fmt.Errorf("timeouts.%s must be >= 0", l.name)
+		}
+	}
+	return nil
+}
+
+// validate checks every per-step retry policy.
+func (r Retries) validate() error {
+	policies := []struct {
+		name   string
+		policy RetryPolicy
+	}{
+		{"touch", r.Touch},
+		{"diff", r.Diff},
+		{"sync", r.Sync},
+		{"scrub", r.Scrub},
+		{"smart", r.Smart},
+		{"status", r.Status},
+		{"repair", r.Repair},
+	}
+	for _, p := range policies {
+		if p.policy.MaxAttempts < 0 {
+			return fmt.Errorf("retry.%s.max_attempts must be >= 0", p.name)
+		}
+		if p.policy.Backoff < 0 {
+			return fmt.Errorf("retry.%s.backoff must be >= 0", p.name)
+		}
+		if p.policy.MaxAttempts > 1 && len(p.policy.ExitCodes) == 0 && len(p.policy.StderrPatterns) == 0 {
+			return fmt.Errorf("retry.%s: max_attempts requires exit_codes or stderr_patterns", p.name)
+		}
+		for _, pattern := range p.policy.StderrPatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("retry.%s.stderr_patterns: invalid pattern %q: %w", p.name, pattern, err)
+			}
+		}
+	}
 	return nil
 }
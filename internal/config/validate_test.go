@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gi8lino/go-snapraid/internal/utils"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +21,7 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    "",
 			SnapraidConfig: "/some/path",
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
@@ -38,7 +39,7 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    nonexistent,
 			SnapraidConfig: "/some/path",
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
@@ -59,7 +60,7 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    binPath,
 			SnapraidConfig: "",
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
@@ -81,7 +82,7 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    binPath,
 			SnapraidConfig: nonexistentCfg,
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
@@ -104,14 +105,14 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    binPath,
 			SnapraidConfig: cfgPath,
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(-5),
+				Plan:      utils.Ptr("-5"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
 
 		err := cfg.Validate()
 		assert.Error(t, err)
-		assert.EqualError(t, err, "scrub.plan must be between 0–100")
+		assert.EqualError(t, err, "scrub.plan must be between 0–100 or one of new, bad, full")
 	})
 
 	t.Run("Scrub.Plan greater than 100 returns error", func(t *testing.T) {
@@ -127,14 +128,14 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    binPath,
 			SnapraidConfig: cfgPath,
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(150),
+				Plan:      utils.Ptr("150"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
 
 		err := cfg.Validate()
 		assert.Error(t, err)
-		assert.EqualError(t, err, "scrub.plan must be between 0–100")
+		assert.EqualError(t, err, "scrub.plan must be between 0–100 or one of new, bad, full")
 	})
 
 	t.Run("Scrub.Plan is nil error", func(t *testing.T) {
@@ -172,7 +173,7 @@ func TestValidate(t *testing.T) {
 			SnapraidBin:    binPath,
 			SnapraidConfig: cfgPath,
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(-3),
 			},
 		}
@@ -212,6 +213,486 @@ scrub:
 		assert.EqualError(t, err, "scrub.older_than must be set")
 	})
 
+	t.Run("Smart failure probability out of range returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Smart: SmartOptions{MaxFailureProbability: utils.Ptr(101)},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "smart.max_failure_probability must be between 0–100 or -1")
+	})
+
+	t.Run("Empty ignore glob returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			IgnoreForThresholds: []string{"*.nfo", " "},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "ignore_for_thresholds[1] must not be empty")
+	})
+
+	t.Run("Negative approval tolerance returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Approval: ApprovalOptions{Tolerance: utils.Ptr(-1)},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "approval.tolerance must be >= 0")
+	})
+
+	t.Run("Negative diff path limit returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Diff: DiffOptions{MaxPaths: utils.Ptr(-1)},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "diff.max_paths must be >= 0")
+	})
+
+	t.Run("Missing spill dir returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Diff: DiffOptions{SpillDir: filepath.Join(tmpDir, "missing")},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "diff.spill_dir not found: "+filepath.Join(tmpDir, "missing"))
+	})
+
+	t.Run("Negative settle interval returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Settle: SettleOptions{Interval: -time.Second},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "settle.interval must be >= 0")
+	})
+
+	t.Run("Zero settle attempts returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Settle: SettleOptions{Interval: time.Minute, Attempts: utils.Ptr(0)},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "settle.attempts must be >= 1")
+	})
+
+	t.Run("Negative target coverage returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:               utils.Ptr("50"),
+				OlderThan:          utils.Ptr(10),
+				TargetCoverageDays: -1,
+			},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "scrub.target_coverage_days must be >= 0")
+	})
+
+	t.Run("Negative max scrub duration returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:        utils.Ptr("50"),
+				OlderThan:   utils.Ptr(10),
+				MaxDuration: -time.Hour,
+			},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "scrub.max_duration must be >= 0")
+	})
+
+	t.Run("Invalid repair policy returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Repair: RepairOptions{Policy: "auto"},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "repair.policy must be one of off, check, fix")
+	})
+
+	t.Run("Included and excluded disks return error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Filters: Filters{Fix: Filter{Disks: []string{"d1"}, ExcludeDisks: []string{"d2"}}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "filters.fix: disks and exclude_disks are mutually exclusive")
+	})
+
+	t.Run("Empty filter path returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Filters: Filters{Check: Filter{Paths: []string{"movies/*", " "}}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "filters.check.paths[1] must not be empty")
+	})
+
+	t.Run("Extra arguments for unknown command return error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Snapraid: SnapraidOptions{ExtraArgs: map[string][]string{"rebuild": {"-v"}}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "snapraid.extra_args: unknown command \"rebuild\"")
+	})
+
+	t.Run("Dangerous extra argument returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Snapraid: SnapraidOptions{ExtraArgs: map[string][]string{"sync": {"-h", "--force-realloc"}}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "snapraid.extra_args.sync: --force-realloc is not allowlisted, set snapraid.allow_dangerous to pass it")
+	})
+
+	t.Run("Empty force entry returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Force: ForceOptions{ZeroSize: []string{"*.lock"}, EmptyDisks: []string{""}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "force.empty_disks[0] must not be empty")
+	})
+
+	t.Run("Negative timeout returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Timeouts: Timeouts{Sync: time.Hour, Scrub: -time.Minute},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "timeouts.scrub must be >= 0")
+	})
+
+	t.Run("Negative repair timeout returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Timeouts: Timeouts{Repair: -time.Minute},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "timeouts.repair must be >= 0")
+	})
+
+	t.Run("Invalid retry pattern returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Retry: Retries{Sync: RetryPolicy{MaxAttempts: 3, StderrPatterns: []string{"("}}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "retry.sync.stderr_patterns: invalid pattern \"(\": error parsing regexp: missing closing ): `(`")
+	})
+
+	t.Run("Negative retry attempts returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Retry: Retries{Touch: RetryPolicy{MaxAttempts: -1}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "retry.touch.max_attempts must be >= 0")
+	})
+
+	t.Run("Retry attempts without exit codes or patterns returns error", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir := t.TempDir()
+		binPath := filepath.Join(tmpDir, "snapraid")
+		cfgPath := filepath.Join(tmpDir, "snapraid.conf")
+		assert.NoError(t, os.WriteFile(binPath, []byte{}, 0o600))
+		assert.NoError(t, os.WriteFile(cfgPath, []byte{}, 0o600))
+
+		cfg := Config{
+			SnapraidBin:    binPath,
+			SnapraidConfig: cfgPath,
+			Scrub: ScrubOptions{
+				Plan:      utils.Ptr("50"),
+				OlderThan: utils.Ptr(10),
+			},
+			Retry: Retries{Scrub: RetryPolicy{MaxAttempts: 3}},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "retry.scrub: max_attempts requires exit_codes or stderr_patterns")
+	})
+
 	t.Run("Valid Config returns no error", func(t *testing.T) {
 		t.Parallel()
 
@@ -226,7 +707,7 @@ scrub:
 			SnapraidBin:    binPath,
 			SnapraidConfig: cfgPath,
 			Scrub: ScrubOptions{
-				Plan:      utils.Ptr(50),
+				Plan:      utils.Ptr("50"),
 				OlderThan: utils.Ptr(10),
 			},
 		}
@@ -235,3 +716,21 @@ scrub:
 		assert.NoError(t, err)
 	})
 }
+
+func TestValidateScrubPlan(t *testing.T) {
+	t.Parallel()
+
+	for _, plan := range []string{"0", "22", "100", "new", "bad", "full"} {
+		t.Run("Valid "+plan, func(t *testing.T) {
+			t.Parallel()
+			assert.NoError(t, ValidateScrubPlan(plan))
+		})
+	}
+
+	for _, plan := range []string{"", "-1", "101", "2.5", "weekly", "NEW"} {
+		t.Run("Invalid "+plan, func(t *testing.T) {
+			t.Parallel()
+			assert.EqualError(t, ValidateScrubPlan(plan), "must be between 0–100 or one of new, bad, full")
+		})
+	}
+}
@@ -3,7 +3,10 @@ package flag
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/gi8lino/go-snapraid/internal/config"
 	"github.com/gi8lino/go-snapraid/internal/logging"
 
 	"github.com/containeroo/tinyflags"
@@ -19,11 +22,12 @@ type ThresholdOptions struct {
 	NoRestore bool // Restore controls whether the "restored files" threshold check is active.
 }
 
-// StepsOptions defines which SnapRAID subcommands ("touch", "scrub", "smart") should run.
+// StepsOptions defines which SnapRAID subcommands ("touch", "scrub", "smart", "status") should run.
 type StepsOptions struct {
-	NoTouch bool // Touch enables the "snapraid touch" step.
-	NoScrub bool // Scrub enables the "snapraid scrub" step.
-	NoSmart bool // Smart enables the "snapraid smart" step.
+	NoTouch  bool // Touch enables the "snapraid touch" step.
+	NoScrub  bool // Scrub enables the "snapraid scrub" step.
+	NoSmart  bool // Smart enables the "snapraid smart" step.
+	NoStatus bool // Status enables the "snapraid status" step.
 }
 
 // Options holds all configuration values parsed from CLI flags.
@@ -36,8 +40,13 @@ type Options struct {
 	NoNotify   bool              // NoNotify disables Slack notifications when true.
 	Steps      StepsOptions      // Steps contains which SnapRAID subcommands ("touch", "scrub", "smart") to execute.
 	Thresholds ThresholdOptions  // Thresholds contains which threshold checks (add/remove/update/…) are enabled.
-	ScrubPlan  int               // ScrubPlan is the percentage (0–100) passed to the "scrub" subcommand.
+	ScrubPlan  string            // ScrubPlan is the percentage (0–100) or keyword passed to the "scrub" subcommand. Empty keeps the configured plan.
 	ScrubOlder int               // ScrubOlder is the "older-than" age (in days) passed to the "scrub" subcommand.
+	Disks      []string          // Disks are "[STEP=]DISK" values replacing the configured disk filters.
+	Paths      []string          // Paths are "[STEP=]PATTERN" values replacing the configured path filters.
+	ExtraArgs  []string          // ExtraArgs are "STEP=ARG" values passed to the snapraid command STEP in addition to the configured ones.
+	NoQuiet    bool              // NoQuiet stops passing "--quiet" to snapraid.
+	Approve    string            // Approve is the timestamp of a blocked run to approve ("approve <timestamp>"). Empty runs SnapRAID.
 }
 
 // ParseFlags parses CLI flags into a structured Options instance. It also handles
@@ -46,6 +55,7 @@ func ParseFlags(args []string, version string) (Options, error) {
 	opts := Options{}
 	tf := tinyflags.NewFlagSet("snapraid-runner", tinyflags.ContinueOnError)
 	tf.Version(version)
+	tf.Note("Use \"approve <timestamp>\" to release the next sync after a run was blocked by thresholds.")
 
 	// Basic
 	tf.StringVar(&opts.ConfigFile, "config", "/etc/snapraid-runner.yml", "Path to snapraid runner config").
@@ -86,6 +96,13 @@ func ParseFlags(args []string, version string) (Options, error) {
 		OneOfGroup("smart").
 		Value()
 
+	status := tf.Bool("status", false, "Enable status step").
+		OneOfGroup("status").
+		Value()
+	noStatus := tf.Bool("no-status", false, "Disable status step").
+		OneOfGroup("status").
+		Value()
+
 	// Threshold disablers
 	noAdd := tf.Bool("no-threshold-add", false, "Disable threshold check for added files").Value()
 	noDel := tf.Bool("no-threshold-del", false, "Disable threshold check for removed files").Value()
@@ -95,26 +112,55 @@ func ParseFlags(args []string, version string) (Options, error) {
 	noRs := tf.Bool("no-threshold-rs", false, "Disable threshold check for restored files").Value()
 
 	// Scrub options
-	tf.IntVar(&opts.ScrubPlan, "plan", 22, "Scrub plan percentage (0–100)").
-		Validate(func(i int) error {
-			if i < 0 || i > 100 {
-				return fmt.Errorf("scrub plan must be between 0 and 100")
+	tf.StringVar(&opts.ScrubPlan, "plan", "", "Scrub plan: percentage (0–100), new, bad or full").
+		Validate(func(s string) error {
+			if err := config.ValidateScrubPlan(s); err != nil {
+				return fmt.Errorf("scrub plan %w", err)
 			}
 			return nil
 		}).
 		Value()
 	tf.IntVar(&opts.ScrubOlder, "older-than", 12, "Scrub files older than N days").Value()
 
+	// Filters
+	tf.StringSliceVar(&opts.Disks, "disk", nil, "Only process this data disk in check and fix, or only in STEP (diff, check or fix)").
+		Placeholder("[STEP=]DISK").
+		Validate(validateFilterValue).
+		Value()
+	tf.StringSliceVar(&opts.Paths, "filter", nil, "Only process files matching this pattern in check and fix, or only in STEP (diff, check or fix)").
+		Placeholder("[STEP=]PATTERN").
+		Validate(validateFilterValue).
+		Value()
+
+	// Snapraid invocation
+	tf.StringSliceVar(&opts.ExtraArgs, "snapraid-arg", nil, "Pass ARG to the snapraid command STEP (subject to the allowlist)").
+		Placeholder("STEP=ARG").
+		Validate(validateExtraArg).
+		Value()
+	tf.BoolVar(&opts.NoQuiet, "no-quiet", false, "Do not pass --quiet to snapraid").Value()
+
 	// Parse args
 	if err := tf.Parse(args); err != nil {
 		return Options{}, err
 	}
 
+	// Resolve the optional "approve <timestamp>" command
+	if args := tf.Args(); len(args) > 0 {
+		if args[0] != "approve" {
+			return Options{}, fmt.Errorf("unknown command %q", args[0])
+		}
+		if len(args) != 2 {
+			return Options{}, fmt.Errorf("usage: approve <timestamp>")
+		}
+		opts.Approve = args[1]
+	}
+
 	// Resolve step toggles: explicit "no-" flags override enables
 	opts.Steps = StepsOptions{
-		NoTouch: *touch && !*noTouch,
-		NoScrub: *scrub && !*noScrub,
-		NoSmart: *smart && !*noSmart,
+		NoTouch:  *touch && !*noTouch,
+		NoScrub:  *scrub && !*noScrub,
+		NoSmart:  *smart && !*noSmart,
+		NoStatus: *status && !*noStatus,
 	}
 
 	// Resolve log format
@@ -140,3 +186,40 @@ func (o Options) Validate() error {
 	}
 	return nil
 }
+
+// filterSteps are the steps accepting disk and path filters.
+var filterSteps = []string{"diff", "check", "fix"}
+
+// filterCommands are the snapraid commands a filter without a step applies to. A diff
+// filter stops the run from syncing, so it has to be asked for explicitly.
+var filterCommands = []string{"check", "fix"}
+
+// splitFilterValue splits a "[STEP=]VALUE" flag value. The step is empty if the
+// value applies to every command in filterCommands.
+func splitFilterValue(s string) (step, value string) {
+	if step, value, ok := strings.Cut(s, "="); ok && slices.Contains(filterSteps, step) {
+		return step, value
+	}
+	return "", s
+}
+
+// validateFilterValue rejects a "[STEP=]VALUE" flag value without a value.
+func validateFilterValue(s string) error {
+	if _, value := splitFilterValue(s); strings.TrimSpace(value) == "" {
+		return fmt.Errorf("filter value must not be empty")
+	}
+	return nil
+}
+
+// validateExtraArg rejects a "STEP=ARG" flag value with an unknown step or without an argument.
+// The argument itself is checked against the allowlist when the config is validated.
+func validateExtraArg(s string) error {
+	step, arg, ok := strings.Cut(s, "=")
+	if !ok || !slices.Contains(config.SnapraidCommands, step) {
+		return fmt.Errorf("snapraid argument must be STEP=ARG with STEP one of %s", strings.Join(config.SnapraidCommands, ", "))
+	}
+	if arg == "" {
+		return fmt.Errorf("snapraid argument must not be empty")
+	}
+	return nil
+}
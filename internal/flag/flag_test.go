@@ -34,10 +34,33 @@ func TestParseFlags(t *testing.T) {
 		assert.True(t, opts.Thresholds.NoMove)
 		assert.True(t, opts.Thresholds.NoRestore)
 		// ScrubPlan/Older defaults
-		assert.Equal(t, 22, opts.ScrubPlan)
+		assert.Empty(t, opts.ScrubPlan)
 		assert.Equal(t, 12, opts.ScrubOlder)
 	})
 
+	t.Run("Approve command", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := ParseFlags([]string{"approve", "2025-06-01T03:00:00Z", "--output-dir", "/var/lib/snapraid"}, "v1.0.0")
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-06-01T03:00:00Z", opts.Approve)
+		assert.Equal(t, "/var/lib/snapraid", opts.OutputDir)
+	})
+
+	t.Run("Approve without timestamp", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"approve"}, "v1.0.0")
+		assert.EqualError(t, err, "usage: approve <timestamp>")
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"resync"}, "v1.0.0")
+		assert.EqualError(t, err, `unknown command "resync"`)
+	})
+
 	t.Run("Help flag", func(t *testing.T) {
 		t.Parallel()
 
@@ -57,16 +80,23 @@ Flags:
         --no-scrub                Disable scrub step [Group: scrub (One Of)]
         --smart                   Enable smart step [Group: smart (One Of)]
         --no-smart                Disable smart step [Group: smart (One Of)]
+        --status                  Enable status step [Group: status (One Of)]
+        --no-status               Disable status step [Group: status (One Of)]
         --no-threshold-add        Disable threshold check for added files
         --no-threshold-del        Disable threshold check for removed files
         --no-threshold-up         Disable threshold check for updated files
         --no-threshold-cp         Disable threshold check for copied files
         --no-threshold-mv         Disable threshold check for moved files
         --no-threshold-rs         Disable threshold check for restored files
-        --plan PLAN               Scrub plan percentage (0–100) (Default: 22)
+        --plan PLAN               Scrub plan: percentage (0–100), new, bad or full
         --older-than OLDER-THAN   Scrub files older than N days (Default: 12)
+        --disk [STEP=]DISK        Only process this data disk in check and fix, or only in STEP (diff, check or fix)
+        --filter [STEP=]PATTERN   Only process files matching this pattern in check and fix, or only in STEP (diff, check or fix)
+        --snapraid-arg STEP=ARG   Pass ARG to the snapraid command STEP (subject to the allowlist)
+        --no-quiet                Do not pass --quiet to snapraid
     -h, --help                    Show help
         --version                 Show version
+Use "approve <timestamp>" to release the next sync after a run was blocked by thresholds.
 `
 		assert.EqualError(t, err, expected)
 	})
@@ -103,6 +133,64 @@ Flags:
 		assert.EqualError(t, err, "only one of the flags in group \"smart\" may be used: --smart vs --no-smart")
 	})
 
+	t.Run("Status and no-status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"--status", "--no-status"}, "v1.0.0")
+		assert.Error(t, err)
+		assert.EqualError(t, err, "only one of the flags in group \"status\" may be used: --status vs --no-status")
+	})
+
+	t.Run("Scrub plan keyword", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := ParseFlags([]string{"--plan", "new"}, "v1.0.0")
+		assert.NoError(t, err)
+		assert.Equal(t, "new", opts.ScrubPlan)
+	})
+
+	t.Run("Invalid scrub plan", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"--plan", "weekly"}, "v1.0.0")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "scrub plan must be between 0–100 or one of new, bad, full")
+	})
+
+	t.Run("Disk and path filters", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := ParseFlags([]string{"--disk", "check=d2", "--disk", "d3", "--disk", "diff=d1", "--filter", "movies/*"}, "v1.0.0")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"check=d2", "d3", "diff=d1"}, opts.Disks)
+		assert.Equal(t, []string{"movies/*"}, opts.Paths)
+	})
+
+	t.Run("Empty filter", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"--disk", "check="}, "v1.0.0")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "filter value must not be empty")
+	})
+
+	t.Run("Extra snapraid arguments", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := ParseFlags([]string{"--snapraid-arg", "sync=--verbose", "--snapraid-arg", "sync=--test-io-cache=8", "--no-quiet"}, "v1.0.0")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"sync=--verbose", "sync=--test-io-cache=8"}, opts.ExtraArgs)
+		assert.True(t, opts.NoQuiet)
+	})
+
+	t.Run("Extra snapraid argument for unknown step", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseFlags([]string{"--snapraid-arg", "rebuild=-v"}, "v1.0.0")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "snapraid argument must be STEP=ARG with STEP one of touch, diff, sync, scrub, smart, status, check, fix")
+	})
+
 	t.Run("Step and threshold resolution", func(t *testing.T) {
 		t.Parallel()
 
@@ -136,7 +224,7 @@ Flags:
 		assert.True(t, opts.Thresholds.NoRestore)
 
 		// Scrub options
-		assert.Equal(t, 55, opts.ScrubPlan)
+		assert.Equal(t, "55", opts.ScrubPlan)
 		assert.Equal(t, 7, opts.ScrubOlder)
 
 		// Other flags
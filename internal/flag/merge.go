@@ -1,6 +1,8 @@
 package flag
 
 import (
+	"strings"
+
 	"github.com/gi8lino/go-snapraid/internal/config"
 	"github.com/gi8lino/go-snapraid/internal/utils"
 )
@@ -16,6 +18,31 @@ func ApplyOverrides(cfg *config.Config, f Options) {
 		cfg.Notify.SlackChannel = ""
 	}
 
+	if f.ScrubPlan != "" {
+		cfg.Scrub.Plan = utils.Ptr(f.ScrubPlan)
+	}
+
+	// CLI filters replace the configured filters of the commands they apply to
+	for step, disks := range groupFilterValues(f.Disks) {
+		filter := filterFor(&cfg.Filters, step)
+		filter.Disks, filter.ExcludeDisks = disks, nil
+	}
+	for step, paths := range groupFilterValues(f.Paths) {
+		filterFor(&cfg.Filters, step).Paths = paths
+	}
+
+	// CLI snapraid arguments are added to the configured ones
+	if f.NoQuiet {
+		cfg.Snapraid.Quiet = utils.Ptr(false)
+	}
+	for _, v := range f.ExtraArgs {
+		step, arg, _ := strings.Cut(v, "=") // validated by ParseFlags
+		if cfg.Snapraid.ExtraArgs == nil {
+			cfg.Snapraid.ExtraArgs = make(map[string][]string)
+		}
+		cfg.Snapraid.ExtraArgs[step] = append(cfg.Snapraid.ExtraArgs[step], arg)
+	}
+
 	// CLI step toggles
 	if f.Steps.NoTouch {
 		cfg.Steps.Touch = utils.Ptr(true)
@@ -26,25 +53,28 @@ func ApplyOverrides(cfg *config.Config, f Options) {
 	if f.Steps.NoSmart {
 		cfg.Steps.Smart = utils.Ptr(true)
 	}
+	if f.Steps.NoStatus {
+		cfg.Steps.Status = utils.Ptr(true)
+	}
 
 	// Threshold disabling
 	if !f.Thresholds.NoAdd {
-		cfg.Thresholds.Add = utils.Ptr(-1)
+		cfg.Thresholds.Add = &config.Threshold{Count: -1}
 	}
 	if !f.Thresholds.NoRemove {
-		cfg.Thresholds.Remove = utils.Ptr(-1)
+		cfg.Thresholds.Remove = &config.Threshold{Count: -1}
 	}
 	if !f.Thresholds.NoUpdate {
-		cfg.Thresholds.Update = utils.Ptr(-1)
+		cfg.Thresholds.Update = &config.Threshold{Count: -1}
 	}
 	if !f.Thresholds.NoCopy {
-		cfg.Thresholds.Copy = utils.Ptr(-1)
+		cfg.Thresholds.Copy = &config.Threshold{Count: -1}
 	}
 	if !f.Thresholds.NoMove {
-		cfg.Thresholds.Move = utils.Ptr(-1)
+		cfg.Thresholds.Move = &config.Threshold{Count: -1}
 	}
 	if !f.Thresholds.NoRestore {
-		cfg.Thresholds.Restore = utils.Ptr(-1)
+		cfg.Thresholds.Restore = &config.Threshold{Count: -1}
 	}
 
 	// Check dry run at the end to override any other flags
@@ -52,5 +82,35 @@ func ApplyOverrides(cfg *config.Config, f Options) {
 		cfg.Steps.Touch = utils.Ptr(false)
 		cfg.Steps.Scrub = utils.Ptr(false)
 		cfg.Steps.Smart = utils.Ptr(false)
+		cfg.Steps.Status = utils.Ptr(false)
+		cfg.Steps.Verify = utils.Ptr(false)
+	}
+}
+
+// groupFilterValues groups "[STEP=]VALUE" flag values by the command they apply to.
+func groupFilterValues(values []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, v := range values {
+		step, value := splitFilterValue(v)
+		if step != "" {
+			groups[step] = append(groups[step], value)
+			continue
+		}
+		for _, cmd := range filterCommands {
+			groups[cmd] = append(groups[cmd], value)
+		}
+	}
+	return groups
+}
+
+// filterFor returns the filter of the named command.
+func filterFor(filters *config.Filters, step string) *config.Filter {
+	switch step {
+	case "diff":
+		return &filters.Diff
+	case "check":
+		return &filters.Check
+	default:
+		return &filters.Fix
 	}
 }
@@ -16,9 +16,10 @@ func TestApplyOverrides(t *testing.T) {
 
 		orig := &config.Config{
 			Steps: config.Steps{
-				Touch: utils.Ptr(true),
-				Scrub: utils.Ptr(true),
-				Smart: utils.Ptr(true),
+				Touch:  utils.Ptr(true),
+				Scrub:  utils.Ptr(true),
+				Smart:  utils.Ptr(true),
+				Verify: utils.Ptr(true),
 			},
 		}
 		flags := Options{DryRun: true}
@@ -27,6 +28,7 @@ func TestApplyOverrides(t *testing.T) {
 		assert.False(t, *orig.Steps.Touch)
 		assert.False(t, *orig.Steps.Scrub)
 		assert.False(t, *orig.Steps.Smart)
+		assert.False(t, *orig.Steps.Verify)
 	})
 
 	t.Run("OutputDir override", func(t *testing.T) {
@@ -39,6 +41,65 @@ func TestApplyOverrides(t *testing.T) {
 		assert.Equal(t, "/new/output", orig.OutputDir)
 	})
 
+	t.Run("Scrub plan override", func(t *testing.T) {
+		t.Parallel()
+
+		orig := &config.Config{Scrub: config.ScrubOptions{Plan: utils.Ptr("22")}}
+		ApplyOverrides(orig, Options{ScrubPlan: "bad"})
+		assert.Equal(t, "bad", *orig.Scrub.Plan)
+
+		ApplyOverrides(orig, Options{})
+		assert.Equal(t, "bad", *orig.Scrub.Plan, "An empty plan keeps the configured one")
+	})
+
+	t.Run("Filter overrides", func(t *testing.T) {
+		t.Parallel()
+
+		orig := &config.Config{Filters: config.Filters{
+			Check: config.Filter{ExcludeDisks: []string{"d1"}},
+			Fix:   config.Filter{Paths: []string{"photos/*"}},
+		}}
+		ApplyOverrides(orig, Options{
+			Disks: []string{"check=d2", "diff=d3"},
+			Paths: []string{"movies/*", "fix=bad/*"},
+		})
+
+		assert.Equal(t, config.Filter{Disks: []string{"d3"}}, orig.Filters.Diff)
+		assert.Equal(t, config.Filter{Disks: []string{"d2"}, Paths: []string{"movies/*"}}, orig.Filters.Check)
+		assert.Equal(t, config.Filter{Paths: []string{"movies/*", "bad/*"}}, orig.Filters.Fix)
+	})
+
+	t.Run("Filters without step apply to check and fix only", func(t *testing.T) {
+		t.Parallel()
+
+		orig := &config.Config{}
+		ApplyOverrides(orig, Options{Disks: []string{"d2"}})
+
+		assert.Equal(t, config.Filters{
+			Check: config.Filter{Disks: []string{"d2"}},
+			Fix:   config.Filter{Disks: []string{"d2"}},
+		}, orig.Filters)
+	})
+
+	t.Run("Snapraid argument overrides", func(t *testing.T) {
+		t.Parallel()
+
+		orig := &config.Config{Snapraid: config.SnapraidOptions{
+			Quiet:     utils.Ptr(true),
+			ExtraArgs: map[string][]string{"sync": {"--pre-hash"}},
+		}}
+		ApplyOverrides(orig, Options{
+			ExtraArgs: []string{"sync=--verbose", "scrub=-v"},
+			NoQuiet:   true,
+		})
+
+		assert.False(t, *orig.Snapraid.Quiet)
+		assert.Equal(t, map[string][]string{
+			"sync":  {"--pre-hash", "--verbose"},
+			"scrub": {"-v"},
+		}, orig.Snapraid.ExtraArgs)
+	})
+
 	t.Run("NoNotify clears Slack settings", func(t *testing.T) {
 		t.Parallel()
 
@@ -56,16 +117,18 @@ func TestApplyOverrides(t *testing.T) {
 		t.Parallel()
 
 		orig := &config.Config{Steps: config.Steps{
-			Touch: utils.Ptr(false),
-			Scrub: utils.Ptr(false),
-			Smart: utils.Ptr(false),
+			Touch:  utils.Ptr(false),
+			Scrub:  utils.Ptr(false),
+			Smart:  utils.Ptr(false),
+			Status: utils.Ptr(false),
 		}}
-		flags := Options{Steps: StepsOptions{NoTouch: true, NoScrub: true, NoSmart: true}}
+		flags := Options{Steps: StepsOptions{NoTouch: true, NoScrub: true, NoSmart: true, NoStatus: true}}
 		ApplyOverrides(orig, flags)
 
 		assert.True(t, *orig.Steps.Touch)
 		assert.True(t, *orig.Steps.Scrub)
 		assert.True(t, *orig.Steps.Smart)
+		assert.True(t, *orig.Steps.Status)
 	})
 
 	t.Run("CLI step toggles set steps", func(t *testing.T) {
@@ -89,12 +152,12 @@ func TestApplyOverrides(t *testing.T) {
 
 		orig := &config.Config{
 			Thresholds: config.Thresholds{
-				Add:     utils.Ptr(5),
-				Remove:  utils.Ptr(10),
-				Update:  utils.Ptr(15),
-				Copy:    utils.Ptr(20),
-				Move:    utils.Ptr(25),
-				Restore: utils.Ptr(30),
+				Add:     &config.Threshold{Count: 5},
+				Remove:  &config.Threshold{Count: 10},
+				Update:  &config.Threshold{Count: 15},
+				Copy:    &config.Threshold{Count: 20},
+				Move:    &config.Threshold{Count: 25},
+				Restore: &config.Threshold{Count: 30},
 			},
 		}
 		flags := Options{Thresholds: ThresholdOptions{
@@ -107,12 +170,12 @@ func TestApplyOverrides(t *testing.T) {
 		}}
 		ApplyOverrides(orig, flags)
 
-		assert.Equal(t, -1, *orig.Thresholds.Add)
-		assert.Equal(t, 10, *orig.Thresholds.Remove)
-		assert.Equal(t, -1, *orig.Thresholds.Update)
-		assert.Equal(t, 20, *orig.Thresholds.Copy)
-		assert.Equal(t, -1, *orig.Thresholds.Move)
-		assert.Equal(t, 30, *orig.Thresholds.Restore)
+		assert.Equal(t, -1, orig.Thresholds.Add.Count)
+		assert.Equal(t, 10, orig.Thresholds.Remove.Count)
+		assert.Equal(t, -1, orig.Thresholds.Update.Count)
+		assert.Equal(t, 20, orig.Thresholds.Copy.Count)
+		assert.Equal(t, -1, orig.Thresholds.Move.Count)
+		assert.Equal(t, 30, orig.Thresholds.Restore.Count)
 	})
 
 	t.Run("Combination: DryRun plus other flags", func(t *testing.T) {
@@ -127,12 +190,12 @@ func TestApplyOverrides(t *testing.T) {
 			OutputDir: "/orig",
 			Notify:    config.Notify{SlackToken: "xyz", SlackChannel: "#x"},
 			Thresholds: config.Thresholds{
-				Add:     utils.Ptr(3),
-				Remove:  utils.Ptr(4),
-				Update:  utils.Ptr(5),
-				Copy:    utils.Ptr(6),
-				Move:    utils.Ptr(7),
-				Restore: utils.Ptr(8),
+				Add:     &config.Threshold{Count: 3},
+				Remove:  &config.Threshold{Count: 4},
+				Update:  &config.Threshold{Count: 5},
+				Copy:    &config.Threshold{Count: 6},
+				Move:    &config.Threshold{Count: 7},
+				Restore: &config.Threshold{Count: 8},
 			},
 		}
 		flags := Options{
@@ -164,11 +227,11 @@ func TestApplyOverrides(t *testing.T) {
 		assert.Empty(t, orig.Notify.SlackChannel)
 
 		// Thresholds: only Update, Move, Restore remain untouched or disabled accordingly
-		assert.Equal(t, -1, *orig.Thresholds.Add)
-		assert.Equal(t, -1, *orig.Thresholds.Remove)
-		assert.Equal(t, 5, *orig.Thresholds.Update)
-		assert.Equal(t, -1, *orig.Thresholds.Copy)
-		assert.Equal(t, 7, *orig.Thresholds.Move)
-		assert.Equal(t, 8, *orig.Thresholds.Restore)
+		assert.Equal(t, -1, orig.Thresholds.Add.Count)
+		assert.Equal(t, -1, orig.Thresholds.Remove.Count)
+		assert.Equal(t, 5, orig.Thresholds.Update.Count)
+		assert.Equal(t, -1, orig.Thresholds.Copy.Count)
+		assert.Equal(t, 7, orig.Thresholds.Move.Count)
+		assert.Equal(t, 8, orig.Thresholds.Restore.Count)
 	})
 }
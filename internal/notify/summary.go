@@ -2,6 +2,8 @@ package notify
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,7 @@ func SendSummaryNotification(
 	result snapraid.RunResult,
 	ts time.Time,
 	timings snapraid.RunTimings,
+	smartLimits snapraid.SmartLimits,
 ) error {
 	statusLabel := "[SUCCESS]"
 	color := "#2ECC71"
@@ -22,11 +25,23 @@ func SendSummaryNotification(
 		statusLabel = "[ERROR]"
 		color = "#E74C3C"
 	}
+	if result.Cancelled() {
+		statusLabel = "[CANCELLED]"
+		color = "#F39C12"
+	}
+	if result.TimedOut() {
+		statusLabel = "[TIMEOUT]"
+		color = "#E67E22"
+	}
+	if result.Deferred {
+		statusLabel = "[DEFERRED]"
+		color = "#F1C40F"
+	}
 	if dryRun {
 		statusLabel = "[DRY RUN]-" + statusLabel
 	}
 
-	msg := formatSlackSummary(result, ts, timings, statusLabel)
+	msg := formatSlackSummary(result, ts, timings, statusLabel, smartLimits)
 
 	if webURL != "" {
 		msg += "\n\n<" + webURL + "|View Results>"
@@ -36,17 +51,58 @@ func SendSummaryNotification(
 }
 
 // formatSlackSummary builds the message text for a Slack notification.
-func formatSlackSummary(result snapraid.RunResult, ts time.Time, timings snapraid.RunTimings, statusLabel string) string {
+func formatSlackSummary(
+	result snapraid.RunResult,
+	ts time.Time,
+	timings snapraid.RunTimings,
+	statusLabel string,
+	smartLimits snapraid.SmartLimits,
+) string {
 	res := result.Result
 	lines := []string{
 		fmt.Sprintf("%s go-snapraid run (%s):", statusLabel, ts.Format("2006-01-02 15:04")),
+	}
+
+	// Show diff warnings first, a missing disk needs immediate attention
+	for _, w := range res.Warnings {
+		switch w.Kind {
+		case snapraid.WarningMissingDisk:
+			lines = append(lines, fmt.Sprintf(":warning: *Disk %s (%s) looks unmounted: all files are missing*", w.Disk, w.Dir))
+		case snapraid.WarningEmptyDisk:
+			lines = append(lines, fmt.Sprintf(":warning: *Disk %s (%s) is empty*", w.Disk, w.Dir))
+		default:
+			lines = append(lines, ":warning: "+w.Message)
+		}
+	}
+
+	lines = append(lines,
 		fmt.Sprintf(" • Equal:    %d", res.Equal),
-		fmt.Sprintf(" • Added:    %d", len(res.Added)),
-		fmt.Sprintf(" • Removed:  %d", len(res.Removed)),
-		fmt.Sprintf(" • Updated:  %d", len(res.Updated)),
-		fmt.Sprintf(" • Moved:    %d", len(res.Moved)),
-		fmt.Sprintf(" • Copied:   %d", len(res.Copied)),
-		fmt.Sprintf(" • Restored: %d", len(res.Restored)),
+		fmt.Sprintf(" • Added:    %d", res.Count("added")),
+		fmt.Sprintf(" • Removed:  %d", res.Count("removed")),
+		fmt.Sprintf(" • Updated:  %d", res.Count("updated")),
+		fmt.Sprintf(" • Moved:    %d", res.Count("moved")),
+		fmt.Sprintf(" • Copied:   %d", res.Count("copied")),
+		fmt.Sprintf(" • Restored: %d", res.Count("restored")),
+	)
+	if result.Ignored != nil {
+		lines = append(lines, fmt.Sprintf(" • Ignored:  %d (not counted against thresholds)", result.Ignored.Changes()))
+	}
+	if res.Truncated() {
+		note := "_Path lists are truncated; counts include every change._"
+		if res.SpillFile != "" {
+			note = fmt.Sprintf("_Path lists are truncated; the full list is in %s._", res.SpillFile)
+		}
+		lines = append(lines, note)
+	}
+
+	// Show where moved and copied files went
+	if len(res.Moves) > 0 {
+		lines = append(lines, "", "Moved:")
+		lines = append(lines, formatTransfers(res.Moves, res.Count("moved")))
+	}
+	if len(res.Copies) > 0 {
+		lines = append(lines, "", "Copied:")
+		lines = append(lines, formatTransfers(res.Copies, res.Count("copied")))
 	}
 
 	// Append timings
@@ -60,12 +116,21 @@ func formatSlackSummary(result snapraid.RunResult, ts time.Time, timings snaprai
 	if timings.Sync > 0 {
 		timingLines = append(timingLines, fmt.Sprintf(" • Sync:   %s", timings.Sync.Truncate(time.Second)))
 	}
+	if timings.Verify > 0 {
+		timingLines = append(timingLines, fmt.Sprintf(" • Verify: %s", timings.Verify.Truncate(time.Second)))
+	}
 	if timings.Scrub > 0 {
 		timingLines = append(timingLines, fmt.Sprintf(" • Scrub:  %s", timings.Scrub.Truncate(time.Second)))
 	}
 	if timings.Smart > 0 {
 		timingLines = append(timingLines, fmt.Sprintf(" • Smart:  %s", timings.Smart.Truncate(time.Second)))
 	}
+	if timings.Status > 0 {
+		timingLines = append(timingLines, fmt.Sprintf(" • Status: %s", timings.Status.Truncate(time.Second)))
+	}
+	if timings.Repair > 0 {
+		timingLines = append(timingLines, fmt.Sprintf(" • Repair: %s", timings.Repair.Truncate(time.Second)))
+	}
 	if timings.Total > 0 {
 		timingLines = append(timingLines, fmt.Sprintf(" • Total:  %s", timings.Total.Truncate(time.Second)))
 	}
@@ -75,11 +140,281 @@ func formatSlackSummary(result snapraid.RunResult, ts time.Time, timings snaprai
 		lines = append(lines, timingLines...)
 	}
 
+	// Show the commands that only processed some disks or paths
+	if len(result.Filters) > 0 {
+		lines = append(lines, "", "Filters:")
+		for _, cmd := range []string{"diff", "check", "fix"} {
+			if f, ok := result.Filters[cmd]; ok {
+				lines = append(lines, fmt.Sprintf(" • %s: %s", cmd, formatFilter(f)))
+			}
+		}
+	}
+
+	// Show how the scrub planner chose the plan
+	if d := result.ScrubPlan; d != nil {
+		plan := "configured plan"
+		if d.Skip {
+			plan = "skipped"
+		} else if d.Plan != "" {
+			plan = fmt.Sprintf("%s%% older than %dd", d.Plan, d.OlderThan)
+		}
+		lines = append(lines, "", fmt.Sprintf("Scrub plan: %s (%s)", plan, d.Reason))
+	}
+
+	// Show error counters reported by sync and scrub
+	var summaryLines []string
+	for _, s := range []struct {
+		step    string
+		summary *snapraid.Summary
+	}{{"Sync", result.Sync}, {"Scrub", result.Scrub}} {
+		if s.summary == nil || !s.summary.HasErrors() {
+			continue
+		}
+		summaryLines = append(summaryLines, fmt.Sprintf(" • %s: %d file, %d I/O, %d data errors",
+			s.step, s.summary.FileErrors, s.summary.IOErrors, s.summary.DataErrors))
+	}
+	if len(summaryLines) > 0 {
+		lines = append(lines, "", "SnapRAID errors:")
+		lines = append(lines, summaryLines...)
+	}
+
+	// Show files that were written while sync was running
+	if changed := result.ChangedDuringSync; changed != nil {
+		lines = append(lines, "", fmt.Sprintf(":warning: *%d files changed during sync and are not fully protected:*", changed.Changes()))
+		lines = append(lines, formatChanged(*changed))
+	}
+
+	// Show the zero-size files and empty disks sync refused
+	if refusal := result.SyncRefusal; refusal != nil {
+		title := ":warning: *Sync refused:*"
+		if len(result.ForcedSync) > 0 {
+			title = fmt.Sprintf("Sync refused, rerun with `%s`:", strings.Join(result.ForcedSync, " "))
+		}
+		lines = append(lines, "", title)
+		for i, f := range refusal.ZeroSize {
+			if i == maxTransfers {
+				lines = append(lines, fmt.Sprintf(" • … and %d more", len(refusal.ZeroSize)-i))
+				break
+			}
+			lines = append(lines, fmt.Sprintf(" • zero size: %s", f.AbsPath))
+		}
+		for _, w := range refusal.EmptyDisks {
+			lines = append(lines, fmt.Sprintf(" • empty disk: %s (%s)", w.Disk, w.Dir))
+		}
+	}
+
+	// Show what the repair did about blocks with errors
+	if rep := result.Repair; rep != nil {
+		lines = append(lines, "", fmt.Sprintf("Repair (%s, %d errors found by %s):", rep.Policy, rep.Errors, rep.Trigger))
+		for _, p := range rep.Phases {
+			status := "ok"
+			if p.Error != "" {
+				status = "failed"
+			} else if p.Summary != nil && p.Summary.HasErrors() {
+				status = fmt.Sprintf("%d errors", p.Summary.FileErrors+p.Summary.IOErrors+p.Summary.DataErrors)
+			}
+			lines = append(lines, fmt.Sprintf(" • %s: %s (%s)", p.Name, status, p.Duration.Truncate(time.Second)))
+		}
+		lines = append(lines, fmt.Sprintf(" • Repaired blocks: %d, unrecoverable: %d", rep.Repaired, rep.Unrecoverable))
+		if rep.Policy == snapraid.RepairFix && !rep.Confirmed() {
+			lines = append(lines, ":warning: *The repair could not be confirmed by scrub*")
+		}
+	}
+
+	// Show every breached threshold
+	if len(result.Violations) > 0 {
+		lines = append(lines, "", "Threshold violations:")
+		lines = append(lines, formatViolationTable(result.Violations))
+		if result.ApprovedRun != "" {
+			lines = append(lines, fmt.Sprintf("Approved by run %s, sync continued.", result.ApprovedRun))
+		} else if result.Fingerprint != "" {
+			lines = append(lines, fmt.Sprintf("Run `go-snapraid approve %s` to release the next sync.", result.Timestamp))
+		}
+	}
+
+	// Show the smart disk table
+	if result.Smart != nil && len(result.Smart.Disks) > 0 {
+		lines = append(lines, "", "Disks:")
+		lines = append(lines, formatSmartTable(*result.Smart, smartLimits))
+	}
+
+	// Show the array status
+	if st := result.Status; st != nil {
+		lines = append(lines, "", "Array status:")
+		if st.ScrubAge != nil {
+			lines = append(lines, fmt.Sprintf(" • Scrub age:    oldest %dd, median %dd, newest %dd",
+				st.ScrubAge.Oldest, st.ScrubAge.Median, st.ScrubAge.Newest))
+		}
+		lines = append(lines,
+			fmt.Sprintf(" • Not scrubbed: %d%%", st.NotScrubbed),
+			fmt.Sprintf(" • Silent errors: %d", st.SilentErrors),
+		)
+		if st.SyncInProgress {
+			lines = append(lines, fmt.Sprintf(" • Sync in progress at %d%%", st.SyncProgress))
+		}
+		for _, w := range st.Warnings {
+			lines = append(lines, " • "+w)
+		}
+	}
+
+	// Show steps that needed more than one attempt, in the order they first ran
+	var steps, retryLines []string
+	for _, a := range result.Attempts {
+		if !slices.Contains(steps, a.Step) {
+			steps = append(steps, a.Step)
+		}
+	}
+	for _, step := range steps {
+		if n := result.AttemptsFor(step); n > 1 {
+			retryLines = append(retryLines, fmt.Sprintf(" • %s: %d attempts", step, n))
+		}
+	}
+	if len(retryLines) > 0 {
+		lines = append(lines, "", "Retries:")
+		lines = append(lines, retryLines...)
+	}
+
+	// Show why sync was postponed
+	if result.Deferred {
+		lines = append(lines, "", fmt.Sprintf("Sync deferred: changes were still settling after %d repeated diffs", result.SettleChecks))
+	}
+
+	// Show steps skipped due to the time budget
+	if len(result.Skipped) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Skipped (time budget): %s", strings.Join(result.Skipped, ", ")))
+	}
+
 	// Show errors
 	if result.Error != nil {
 		lines = append(lines, "", "Errors:")
+		if result.Cancelled() {
+			lines = append(lines, fmt.Sprintf("Cancelled during %s", result.FailedStep))
+		}
+		if result.TimedOut() {
+			lines = append(lines, fmt.Sprintf("Timed out during %s", result.FailedStep))
+		}
 		lines = append(lines, result.Error.Error())
 	}
 
 	return strings.Join(lines, "\n")
 }
+
+// formatViolationTable renders the threshold violations as a monospace table.
+func formatViolationTable(violations snapraid.ThresholdViolations) string {
+	rows := []string{fmt.Sprintf("%-12s %8s %8s  %s", "Category", "Count", "Limit", "Rule")}
+	for _, v := range violations {
+		if v.Disk != "" {
+			rows = append(rows, fmt.Sprintf("%-12s %s (%s)", v.Category, v.Disk, v.Dir))
+			continue
+		}
+		limit := strconv.Itoa(v.Limit)
+		if v.Percent > 0 {
+			limit = fmt.Sprintf("%d (%g%%)", v.Limit, v.Percent)
+		}
+		rule := v.Rule
+		if rule == "" {
+			rule = "global"
+		}
+		rows = append(rows, fmt.Sprintf("%-12s %8d %8s  %s", v.Category, v.Count, limit, rule))
+	}
+	return "```\n" + strings.Join(rows, "\n") + "\n```"
+}
+
+// maxTransfers is the number of moves or copies listed in a notification.
+const maxTransfers = 10
+
+// formatTransfers renders up to maxTransfers transfers as a monospace list.
+// total is the number of transfers including those not listed in the diff.
+func formatTransfers(transfers []snapraid.Transfer, total int) string {
+	rows := make([]string, 0, maxTransfers+1)
+	for i, t := range transfers {
+		if i == maxTransfers {
+			break
+		}
+		rows = append(rows, t.String())
+	}
+	if total > len(rows) {
+		rows = append(rows, fmt.Sprintf("… and %d more", total-len(rows)))
+	}
+	return "```\n" + strings.Join(rows, "\n") + "\n```"
+}
+
+// maxChanged is the number of files changed during sync listed in a notification.
+const maxChanged = 10
+
+// formatChanged renders up to maxChanged changed files of d as a monospace list.
+func formatChanged(d snapraid.DiffResult) string {
+	rows := make([]string, 0, maxChanged+1)
+	for _, c := range []struct {
+		change string
+		paths  []string
+	}{
+		{"added", d.Added},
+		{"removed", d.Removed},
+		{"updated", d.Updated},
+		{"moved", d.Moved},
+		{"copied", d.Copied},
+		{"restored", d.Restored},
+	} {
+		for _, p := range c.paths {
+			if len(rows) < maxChanged {
+				rows = append(rows, c.change+" "+p)
+			}
+		}
+	}
+	if total := d.Changes(); total > len(rows) {
+		rows = append(rows, fmt.Sprintf("… and %d more", total-len(rows)))
+	}
+	return "```\n" + strings.Join(rows, "\n") + "\n```"
+}
+
+// formatSmartTable renders the smart report as a monospace table.
+// Disks over a limit are marked with "!".
+func formatSmartTable(rep snapraid.SmartReport, limits snapraid.SmartLimits) string {
+	rows := []string{fmt.Sprintf("  %-8s %-10s %5s %5s %6s", "Disk", "Device", "Temp", "FP", "Errors")}
+	for _, d := range rep.Disks {
+		mark := " "
+		if d.ExceedsLimits(limits) {
+			mark = "!"
+		}
+		errs := optionalInt(d.ErrorCount, "")
+		if d.ErrorStatus != "" {
+			errs = d.ErrorStatus
+		}
+		rows = append(rows, fmt.Sprintf("%s %-8s %-10s %5s %5s %6s",
+			mark,
+			d.Disk,
+			d.Device,
+			optionalInt(d.Temperature, "°C"),
+			optionalInt(d.FailureProbability, "%"),
+			errs,
+		))
+	}
+	if rep.FailureProbability != nil {
+		rows = append(rows, "", fmt.Sprintf("Array failure probability: %d%%", *rep.FailureProbability))
+	}
+	return "```\n" + strings.Join(rows, "\n") + "\n```"
+}
+
+// optionalInt formats v with the given unit, or "-" if v is nil.
+func optionalInt(v *int, unit string) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d%s", *v, unit)
+}
+
+// formatFilter describes the disks and paths a filter selects.
+func formatFilter(f snapraid.Filter) string {
+	var parts []string
+	if len(f.Disks) > 0 {
+		parts = append(parts, "disks "+strings.Join(f.Disks, ", "))
+	}
+	if len(f.ExcludeDisks) > 0 {
+		parts = append(parts, "all disks except "+strings.Join(f.ExcludeDisks, ", "))
+	}
+	if len(f.Paths) > 0 {
+		parts = append(parts, "paths "+strings.Join(f.Paths, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
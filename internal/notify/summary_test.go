@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gi8lino/go-snapraid/internal/utils"
+	"github.com/gi8lino/go-snapraid/pkg/snapraid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSlackSummary(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 6, 2, 14, 30, 0, 0, time.UTC)
+	limits := snapraid.SmartLimits{FailureProbability: 50, Temperature: 45}
+	removed := snapraid.ThresholdViolations{{Category: "removed", Count: 120, Limit: 100}}
+
+	tests := []struct {
+		name    string
+		result  snapraid.RunResult
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "Blocked run shows the approval hint",
+			result: snapraid.RunResult{
+				Timestamp:   "2025-06-02T14:30:00Z",
+				Violations:  removed,
+				Fingerprint: "abc123",
+				Error:       removed,
+				ErrorKind:   snapraid.ErrorKindThreshold,
+			},
+			want: []string{
+				"Threshold violations:",
+				"removed           120      100  global",
+				"Run `go-snapraid approve 2025-06-02T14:30:00Z` to release the next sync.",
+			},
+			notWant: []string{"Approved by run"},
+		},
+		{
+			name: "Approved run names the approval",
+			result: snapraid.RunResult{
+				Violations:  removed,
+				ApprovedRun: "2025-06-01T03:00:00Z",
+			},
+			want:    []string{"Approved by run 2025-06-01T03:00:00Z, sync continued."},
+			notWant: []string{"go-snapraid approve"},
+		},
+		{
+			name: "Violations per disk and rule",
+			result: snapraid.RunResult{
+				Violations: snapraid.ThresholdViolations{
+					{Category: "missing_disk", Disk: "d2", Dir: "/mnt/d2/"},
+					{Category: "added", Count: 30, Limit: 20, Percent: 1.5, Rule: "photos/**"},
+				},
+				Fingerprint: "abc123",
+			},
+			want: []string{
+				"missing_disk d2 (/mnt/d2/)",
+				"added              30 20 (1.5%)  photos/**",
+			},
+		},
+		{
+			name: "Smart table marks disks over the limits",
+			result: snapraid.RunResult{
+				Smart: &snapraid.SmartReport{
+					Disks: []snapraid.SmartDisk{
+						{Disk: "d1", Device: "/dev/sda", Temperature: utils.Ptr(35), FailureProbability: utils.Ptr(4), ErrorCount: utils.Ptr(0)},
+						{Disk: "d2", Device: "/dev/sdb", Temperature: utils.Ptr(50), FailureProbability: utils.Ptr(4), ErrorCount: utils.Ptr(0)},
+						{Disk: "parity", Device: "/dev/sdc", ErrorStatus: "logfail"},
+					},
+					FailureProbability: utils.Ptr(12),
+				},
+			},
+			want: []string{
+				"Disks:",
+				"  d1       /dev/sda    35°C    4%      0",
+				"! d2       /dev/sdb    50°C    4%      0",
+				"! parity   /dev/sdc       -     - logfail",
+				"Array failure probability: 12%",
+			},
+		},
+		{
+			name: "Missing disk warning comes first",
+			result: snapraid.RunResult{
+				Result: snapraid.DiffResult{Warnings: []snapraid.DiffWarning{
+					{Kind: snapraid.WarningMissingDisk, Disk: "d2", Dir: "/mnt/d2/"},
+				}},
+			},
+			want: []string{"[SUCCESS] go-snapraid run (2025-06-02 14:30):\n:warning: *Disk d2 (/mnt/d2/) looks unmounted: all files are missing*"},
+		},
+		{
+			name: "Cancelled run",
+			result: snapraid.RunResult{
+				Error:      fmt.Errorf("snapraid sync failed: %w", context.Canceled),
+				ErrorKind:  snapraid.ErrorKindCancelled,
+				FailedStep: "sync",
+			},
+			want: []string{"Errors:", "Cancelled during sync"},
+		},
+		{
+			name: "Timed out run",
+			result: snapraid.RunResult{
+				Error:      &snapraid.TimeoutError{Step: "scrub", Limit: time.Hour},
+				ErrorKind:  snapraid.ErrorKindTimeout,
+				FailedStep: "scrub",
+				Skipped:    []string{"smart"},
+			},
+			want: []string{"Timed out during scrub", "scrub timed out after 1h0m0s", "Skipped (time budget): smart"},
+		},
+		{
+			name: "Repair phases",
+			result: snapraid.RunResult{
+				Repair: &snapraid.RepairReport{
+					Policy:   snapraid.RepairFix,
+					Trigger:  "scrub",
+					Errors:   3,
+					Repaired: 3,
+					Phases: []snapraid.RepairPhase{
+						{Name: "fix", Summary: &snapraid.Summary{}, Duration: 90 * time.Second},
+						{Name: "rescrub", Summary: &snapraid.Summary{DataErrors: 1}, Duration: time.Minute},
+					},
+				},
+			},
+			want: []string{
+				"Repair (fix, 3 errors found by scrub):",
+				" • fix: ok (1m30s)",
+				" • rescrub: 1 errors (1m0s)",
+				" • Repaired blocks: 3, unrecoverable: 0",
+				":warning: *The repair could not be confirmed by scrub*",
+			},
+		},
+		{
+			name: "Filters",
+			result: snapraid.RunResult{
+				Filters: map[string]snapraid.Filter{
+					"diff":  {Paths: []string{"photos/"}},
+					"check": {Disks: []string{"d1", "d2"}},
+					"fix":   {ExcludeDisks: []string{"d3"}, Paths: []string{"movies/*"}},
+				},
+			},
+			want: []string{
+				"Filters:\n • diff: paths photos/\n • check: disks d1, d2\n • fix: all disks except d3; paths movies/*",
+			},
+		},
+		{
+			name: "Retries",
+			result: snapraid.RunResult{
+				Attempts: []snapraid.Attempt{
+					{Step: "diff", Attempt: 1},
+					{Step: "sync", Attempt: 1},
+					{Step: "sync", Attempt: 2},
+					{Step: "check", Attempt: 1},
+					{Step: "check", Attempt: 2},
+					{Step: "check", Attempt: 3},
+				},
+			},
+			want:    []string{"Retries:\n • sync: 2 attempts\n • check: 3 attempts"},
+			notWant: []string{"diff:"},
+		},
+		{
+			name: "Deferred sync",
+			result: snapraid.RunResult{
+				Deferred:     true,
+				SettleChecks: 3,
+			},
+			want: []string{"Sync deferred: changes were still settling after 3 repeated diffs"},
+		},
+		{
+			name: "Forced sync refusal",
+			result: snapraid.RunResult{
+				SyncRefusal: &snapraid.SyncRefusal{ZeroSize: []snapraid.ZeroSizeFile{{AbsPath: "/mnt/d1/db.lock"}}},
+				ForcedSync:  []string{snapraid.ForceZero},
+			},
+			want:    []string{"Sync refused, rerun with `--force-zero`:\n • zero size: /mnt/d1/db.lock"},
+			notWant: []string{":warning: *Sync refused:*"},
+		},
+		{
+			name: "Skipped scrub plan",
+			result: snapraid.RunResult{
+				ScrubPlan: &snapraid.ScrubDecision{Skip: true, Capped: true, Reason: "less than 1% fits, scrub skipped"},
+			},
+			want: []string{"Scrub plan: skipped (less than 1% fits, scrub skipped)"},
+		},
+		{
+			name:    "Successful run without extras",
+			result:  snapraid.RunResult{Result: snapraid.DiffResult{Equal: 5}},
+			want:    []string{" • Equal:    5"},
+			notWant: []string{"Errors:", "Threshold violations:", "Disks:", "Repair", "Filters:", "Retries:"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			msg := formatSlackSummary(tc.result, ts, tc.result.Timings, "[SUCCESS]", limits)
+			for _, want := range tc.want {
+				assert.Contains(t, msg, want)
+			}
+			for _, notWant := range tc.notWant {
+				assert.NotContains(t, msg, notWant)
+			}
+		})
+	}
+}
+
+func TestFormatTransfers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Names differing disks", func(t *testing.T) {
+		t.Parallel()
+
+		out := formatTransfers([]snapraid.Transfer{{From: "a.txt", To: "b.txt", FromDisk: "d1", ToDisk: "d2"}}, 1)
+		assert.Equal(t, "```\na.txt (d1) -> b.txt (d2)\n```", out)
+	})
+
+	t.Run("Counts transfers over the limit", func(t *testing.T) {
+		t.Parallel()
+
+		var transfers []snapraid.Transfer
+		for i := range maxTransfers + 2 {
+			transfers = append(transfers, snapraid.Transfer{From: fmt.Sprintf("%d", i), To: "x"})
+		}
+		out := formatTransfers(transfers, 15)
+		assert.Contains(t, out, "9 -> x\n… and 5 more")
+		assert.NotContains(t, out, "10 -> x")
+	})
+}
+
+func TestFormatChanged(t *testing.T) {
+	t.Parallel()
+
+	d := snapraid.DiffResult{Added: []string{"a.txt"}, Updated: []string{"b.txt"}}
+	assert.Equal(t, "```\nadded a.txt\nupdated b.txt\n```", formatChanged(d))
+}
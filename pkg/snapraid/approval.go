@@ -0,0 +1,163 @@
+package snapraid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// approvalFile is the name of the pending approval in the output directory.
+// It has no ".json" suffix so it is not mistaken for a run result.
+const approvalFile = ".approval"
+
+// Approval releases a sync that was blocked by the threshold gate. It is consumed by
+// the next run whose diff matches the approved one.
+type Approval struct {
+	Timestamp   string     `json:"timestamp"`   // timestamp of the blocked run that was approved
+	Fingerprint string     `json:"fingerprint"` // fingerprint of the approved diff
+	ApprovedAt  time.Time  `json:"approved_at"` // when the approval was given
+	Result      DiffResult `json:"result"`      // approved diff, used for tolerance matching
+}
+
+// Fingerprint returns a stable hash over every change entry, the number of entries omitted
+// from the lists and every blocking warning. The order of entries does not matter.
+func (d DiffResult) Fingerprint() string {
+	h := sha256.New()
+	for i, list := range d.entries() {
+		sorted := slices.Sorted(slices.Values(list))
+		for _, entry := range sorted {
+			fmt.Fprintf(h, "%s\x00%s\n", changeCategories[i], entry) // nolint:errcheck
+		}
+		if n := d.Omitted[changeCategories[i]]; n > 0 {
+			fmt.Fprintf(h, "%s\x00omitted\x00%d\n", changeCategories[i], n) // nolint:errcheck
+		}
+	}
+	for _, w := range d.BlockingWarnings() {
+		fmt.Fprintf(h, "%s\x00%s\n", w.Kind, w.Disk) // nolint:errcheck
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// distance returns how many change entries are in only one of a and b.
+// Entries omitted from the lists can only be compared by their number.
+func distance(a, b DiffResult) int {
+	n := 0
+	for _, category := range changeCategories {
+		diff := a.Omitted[category] - b.Omitted[category]
+		n += max(diff, -diff)
+	}
+	bEntries := b.entries()
+	for i, list := range a.entries() {
+		inA := make(map[string]bool, len(list))
+		for _, e := range list {
+			inA[e] = true
+		}
+		inB := make(map[string]bool, len(bEntries[i]))
+		for _, e := range bEntries[i] {
+			inB[e] = true
+			if !inA[e] {
+				n++
+			}
+		}
+		for e := range inA {
+			if !inB[e] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// matches reports whether d equals the approved diff, allowing up to tolerance differing entries.
+// Blocking warnings must always match exactly.
+func (a Approval) matches(d DiffResult, tolerance int) bool {
+	if d.Fingerprint() == a.Fingerprint {
+		return true
+	}
+	if !slices.Equal(warningKeys(a.Result), warningKeys(d)) {
+		return false
+	}
+	return distance(a.Result, d) <= tolerance
+}
+
+// warningKeys identifies the blocking warnings of d.
+func warningKeys(d DiffResult) []string {
+	var keys []string
+	for _, w := range d.BlockingWarnings() {
+		keys = append(keys, string(w.Kind)+":"+w.Disk)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Approve marks the blocked run with the given timestamp as approved. It reads the run's
+// result from dir and stores a pending approval there, replacing any previous one.
+func Approve(dir, timestamp string) (Approval, error) {
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		return Approval{}, fmt.Errorf("invalid run timestamp %q: %w", timestamp, err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, timestamp+".json"))
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to read run result: %w", err)
+	}
+
+	var run struct {
+		Result      DiffResult `json:"result"`
+		ErrorKind   ErrorKind  `json:"error_kind"`
+		Fingerprint string     `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return Approval{}, fmt.Errorf("invalid run result: %w", err)
+	}
+	if run.ErrorKind != ErrorKindThreshold || run.Fingerprint == "" {
+		return Approval{}, fmt.Errorf("run %s was not blocked by thresholds", timestamp)
+	}
+
+	approval := Approval{
+		Timestamp:   timestamp,
+		Fingerprint: run.Fingerprint,
+		ApprovedAt:  time.Now(),
+		Result:      run.Result,
+	}
+
+	data, err := json.MarshalIndent(approval, "", "  ")
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to encode approval: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, approvalFile), data, 0o644); err != nil {
+		return Approval{}, fmt.Errorf("failed to write approval: %w", err)
+	}
+	return approval, nil
+}
+
+// loadApproval reads the pending approval from dir. It returns nil if there is none.
+func loadApproval(dir string) (*Approval, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, approvalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval: %w", err)
+	}
+
+	var a Approval
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, fmt.Errorf("invalid approval: %w", err)
+	}
+	return &a, nil
+}
+
+// removeApproval deletes the pending approval from dir so it is used only once.
+func removeApproval(dir string) error {
+	if err := os.Remove(filepath.Join(dir, approvalFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove approval: %w", err)
+	}
+	return nil
+}
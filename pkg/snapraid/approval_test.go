@@ -0,0 +1,130 @@
+package snapraid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Order does not matter", func(t *testing.T) {
+		t.Parallel()
+
+		a := DiffResult{Removed: []string{"a.txt", "b.txt"}}
+		b := DiffResult{Removed: []string{"b.txt", "a.txt"}, Equal: 10}
+		assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+	})
+
+	t.Run("Category matters", func(t *testing.T) {
+		t.Parallel()
+
+		a := DiffResult{Removed: []string{"a.txt"}}
+		b := DiffResult{Added: []string{"a.txt"}}
+		assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+	})
+
+	t.Run("Blocking warnings matter", func(t *testing.T) {
+		t.Parallel()
+
+		a := DiffResult{}
+		b := DiffResult{Warnings: []DiffWarning{{Kind: WarningMissingDisk, Disk: "d1"}}}
+		assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+	})
+}
+
+func TestApprovalMatches(t *testing.T) {
+	t.Parallel()
+
+	approved := DiffResult{Removed: []string{"a.txt", "b.txt"}}
+	a := Approval{Fingerprint: approved.Fingerprint(), Result: approved}
+
+	t.Run("Exact match", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, a.matches(DiffResult{Removed: []string{"b.txt", "a.txt"}}, 0))
+	})
+
+	t.Run("Differences within tolerance", func(t *testing.T) {
+		t.Parallel()
+		d := DiffResult{Removed: []string{"a.txt", "c.txt"}}
+		assert.False(t, a.matches(d, 1))
+		assert.True(t, a.matches(d, 2))
+	})
+
+	t.Run("Blocking warnings must match", func(t *testing.T) {
+		t.Parallel()
+		d := approved
+		d.Warnings = []DiffWarning{{Kind: WarningEmptyDisk, Disk: "d2"}}
+		assert.False(t, a.matches(d, 100))
+	})
+}
+
+func TestApprove(t *testing.T) {
+	t.Parallel()
+
+	const ts = "2025-06-01T03:00:00Z"
+
+	t.Run("Approves blocked run", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		diff := DiffResult{Removed: []string{"a.txt"}}
+		run := RunResult{Timestamp: ts, Result: diff, ErrorKind: ErrorKindThreshold, Fingerprint: diff.Fingerprint()}
+		assert.NoError(t, run.WriteJSON(dir))
+
+		approval, err := Approve(dir, ts)
+		assert.NoError(t, err)
+		assert.Equal(t, ts, approval.Timestamp)
+		assert.Equal(t, diff.Fingerprint(), approval.Fingerprint)
+
+		loaded, err := loadApproval(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, approval.Fingerprint, loaded.Fingerprint)
+		assert.Equal(t, diff.Removed, loaded.Result.Removed)
+	})
+
+	t.Run("Run was not blocked", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		assert.NoError(t, RunResult{Timestamp: ts}.WriteJSON(dir))
+
+		_, err := Approve(dir, ts)
+		assert.EqualError(t, err, "run 2025-06-01T03:00:00Z was not blocked by thresholds")
+	})
+
+	t.Run("Invalid timestamp", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Approve(t.TempDir(), "../etc/passwd")
+		assert.ErrorContains(t, err, `invalid run timestamp "../etc/passwd"`)
+	})
+
+	t.Run("Missing run", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Approve(t.TempDir(), ts)
+		assert.ErrorContains(t, err, "failed to read run result")
+	})
+
+	t.Run("No pending approval", func(t *testing.T) {
+		t.Parallel()
+
+		approval, err := loadApproval(t.TempDir())
+		assert.NoError(t, err)
+		assert.Nil(t, approval)
+	})
+
+	t.Run("Remove approval", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, approvalFile), []byte("{}"), 0o600))
+		assert.NoError(t, removeApproval(dir))
+		assert.NoFileExists(t, filepath.Join(dir, approvalFile))
+		assert.NoError(t, removeApproval(dir))
+	})
+}
@@ -0,0 +1,169 @@
+package snapraid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DataDisk is a data disk declared in the snapraid configuration.
+type DataDisk struct {
+	Name string // Name is the snapraid disk name, e.g. "d1".
+	Dir  string // Dir is the mount point of the disk, e.g. "/mnt/disk1/".
+}
+
+// parseDataDisks reads the "data" (and legacy "disk") lines of a snapraid configuration,
+// e.g. "data d1 /mnt/disk1/". Mount points may contain spaces.
+func parseDataDisks(r io.Reader) []DataDisk {
+	var disks []DataDisk
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || (fields[0] != "data" && fields[0] != "disk") {
+			continue
+		}
+		dir := strings.Join(fields[2:], " ")
+		disks = append(disks, DataDisk{Name: fields[1], Dir: dir})
+	}
+	return disks
+}
+
+// loadDataDisks reads the data disks from the snapraid configuration at path.
+func loadDataDisks(path string) ([]DataDisk, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapraid config: %w", err)
+	}
+	defer f.Close() // nolint:errcheck
+
+	return parseDataDisks(f), nil
+}
+
+// diskByName returns the data disk with the given name.
+func diskByName(disks []DataDisk, name string) (DataDisk, bool) {
+	for _, d := range disks {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return DataDisk{}, false
+}
+
+// diskByPath returns the data disk whose mount point is the longest prefix of the absolute
+// path, together with the path relative to that mount point.
+func diskByPath(disks []DataDisk, path string) (DataDisk, string, bool) {
+	var best DataDisk
+	var rel string
+	found := false
+
+	for _, d := range disks {
+		dir := strings.TrimSuffix(d.Dir, "/") + "/"
+		if !strings.HasPrefix(path, dir) || (found && len(dir) <= len(best.Dir)) {
+			continue
+		}
+		best, rel, found = DataDisk{Name: d.Name, Dir: dir}, strings.TrimPrefix(path, dir), true
+	}
+	return best, rel, found
+}
+
+// diskByContent returns the only data disk holding a file at the disk-relative path rel.
+// Files that exist on several disks or no longer exist, e.g. removed ones, are not attributed.
+func diskByContent(disks []DataDisk, rel string) (DataDisk, bool) {
+	var found []DataDisk
+	for _, d := range disks {
+		if _, err := os.Lstat(filepath.Join(d.Dir, rel)); err == nil {
+			found = append(found, d)
+		}
+	}
+	if len(found) != 1 {
+		return DataDisk{}, false
+	}
+	return found[0], true
+}
+
+// newEntry creates a diff entry for path. Paths from the tag log are relative to the disk.
+// Depending on the snapraid version, the text output prints them absolute or relative to
+// a disk it does not name.
+func newEntry(change, disk, path string) DiffEntry {
+	if filepath.IsAbs(path) {
+		return DiffEntry{Change: change, Disk: disk, AbsPath: path}
+	}
+	return DiffEntry{Change: change, Disk: disk, RelPath: path}
+}
+
+// resolve fills in the missing disk, relative or absolute path of e from the data disks.
+// A relative path without a disk is attributed to the only disk holding the file. An entry
+// that cannot be attributed is marked unresolved.
+func (e *DiffEntry) resolve(disks []DataDisk) {
+	switch {
+	case e.Disk != "":
+		d, ok := diskByName(disks, e.Disk)
+		if !ok {
+			e.Unresolved = true
+			return
+		}
+		if e.AbsPath == "" {
+			e.AbsPath = filepath.Join(d.Dir, e.RelPath)
+		}
+	case e.AbsPath != "":
+		d, rel, ok := diskByPath(disks, e.AbsPath)
+		if !ok {
+			e.Unresolved = true
+			return
+		}
+		e.Disk, e.RelPath = d.Name, rel
+	default:
+		d, ok := diskByContent(disks, e.RelPath)
+		if !ok {
+			e.Unresolved = true
+			return
+		}
+		e.Disk, e.AbsPath = d.Name, filepath.Join(d.Dir, e.RelPath)
+	}
+}
+
+// resolve fills in the data disks and disk-relative paths of both ends of the transfer.
+func (t *Transfer) resolve(disks []DataDisk) {
+	t.FromDisk, t.FromRelPath = resolvePath(disks, t.FromDisk, t.From)
+	t.ToDisk, t.ToRelPath = resolvePath(disks, t.ToDisk, t.To)
+}
+
+// resolvePath returns the data disk and disk-relative path of path, keeping a known disk.
+// Absolute paths are attributed by mount point, relative ones by looking for the file.
+func resolvePath(disks []DataDisk, disk, path string) (string, string) {
+	if path == "" {
+		return disk, ""
+	}
+	if !filepath.IsAbs(path) {
+		if disk == "" {
+			if d, ok := diskByContent(disks, path); ok {
+				disk = d.Name
+			}
+		}
+		return disk, path
+	}
+	if d, rel, ok := diskByPath(disks, path); ok {
+		if disk == "" {
+			disk = d.Name
+		}
+		return disk, rel
+	}
+	return disk, ""
+}
+
+// resolveDisks attributes every entry and transfer of d to its data disk.
+func (d *DiffResult) resolveDisks(disks []DataDisk) {
+	for i := range d.Entries {
+		d.Entries[i].resolve(disks)
+	}
+	for i := range d.Moves {
+		d.Moves[i].resolve(disks)
+	}
+	for i := range d.Copies {
+		d.Copies[i].resolve(disks)
+	}
+}
@@ -0,0 +1,103 @@
+package snapraid
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDataDisks(t *testing.T) {
+	t.Parallel()
+
+	conf := `# SnapRAID configuration
+parity /mnt/parity1/snapraid.parity
+content /var/snapraid/snapraid.content
+data d1 /mnt/disk1/
+disk d2 /mnt/disk2
+data d3 /mnt/my disk/
+exclude *.unrecoverable
+`
+	disks := parseDataDisks(strings.NewReader(conf))
+	assert.Equal(t, []DataDisk{
+		{Name: "d1", Dir: "/mnt/disk1/"},
+		{Name: "d2", Dir: "/mnt/disk2"},
+		{Name: "d3", Dir: "/mnt/my disk/"},
+	}, disks)
+}
+
+func TestResolveDisks(t *testing.T) {
+	t.Parallel()
+
+	disks := []DataDisk{
+		{Name: "d1", Dir: "/mnt/disk1/"},
+		{Name: "d10", Dir: "/mnt/disk10"},
+		{Name: "nested", Dir: "/mnt/disk1/nested/"},
+	}
+
+	t.Run("Entry with disk name", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEntry("added", "d10", "movies/a.mkv")
+		e.resolve(disks)
+		assert.Equal(t, DiffEntry{Change: "added", Disk: "d10", RelPath: "movies/a.mkv", AbsPath: "/mnt/disk10/movies/a.mkv"}, e)
+	})
+
+	t.Run("Absolute path uses longest mount point", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEntry("removed", "", "/mnt/disk1/nested/a.txt")
+		e.resolve(disks)
+		assert.Equal(t, DiffEntry{Change: "removed", Disk: "nested", RelPath: "a.txt", AbsPath: "/mnt/disk1/nested/a.txt"}, e)
+
+		e = newEntry("removed", "", "/mnt/disk10/b.txt")
+		e.resolve(disks)
+		assert.Equal(t, "d10", e.Disk, "a mount point must not match as a plain string prefix")
+	})
+
+	t.Run("Unknown disk stays unresolved", func(t *testing.T) {
+		t.Parallel()
+
+		d := DiffResult{Entries: []DiffEntry{
+			newEntry("added", "d9", "a.txt"),
+			newEntry("added", "", "/srv/b.txt"),
+			newEntry("added", "", "c.txt"),
+		}}
+		d.resolveDisks(disks)
+		assert.Equal(t, []DiffEntry{
+			{Change: "added", Disk: "d9", RelPath: "a.txt", Unresolved: true},
+			{Change: "added", AbsPath: "/srv/b.txt", Unresolved: true},
+			{Change: "added", RelPath: "c.txt", Unresolved: true},
+		}, d.Entries)
+	})
+
+	t.Run("Relative text output is matched against disk content", func(t *testing.T) {
+		t.Parallel()
+
+		root := t.TempDir()
+		local := []DataDisk{{Name: "d1", Dir: filepath.Join(root, "d1")}, {Name: "d2", Dir: filepath.Join(root, "d2")}}
+		for _, path := range []string{"d1/movies/Interstellar (2014)/Interstellar.mkv", "d2/movies/Mile 22 (2002)/Mile.22.mkv", "d1/shared.txt", "d2/shared.txt"} {
+			path = filepath.Join(root, path)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			assert.NoError(t, os.WriteFile(path, nil, 0o644))
+		}
+
+		d := parseDiff([]string{
+			"add movies/Interstellar\\ \\(2014\\)/Interstellar.mkv",
+			"copy movies/Mile\\ 22\\ \\(2002\\)/Mile.22.mkv",
+			"remove movies/XOXO.mkv",
+			"update shared.txt",
+		})
+		d.resolveDisks(local)
+
+		assert.Equal(t, []DiffEntry{
+			{Change: "added", Disk: "d1", RelPath: "movies/Interstellar (2014)/Interstellar.mkv", AbsPath: filepath.Join(root, "d1", "movies/Interstellar (2014)/Interstellar.mkv")},
+			{Change: "copied", Disk: "d2", RelPath: "movies/Mile 22 (2002)/Mile.22.mkv", AbsPath: filepath.Join(root, "d2", "movies/Mile 22 (2002)/Mile.22.mkv")},
+			{Change: "removed", RelPath: "movies/XOXO.mkv", Unresolved: true},
+			{Change: "updated", RelPath: "shared.txt", Unresolved: true},
+		}, d.Entries)
+		assert.Equal(t, []Transfer{{To: "movies/Mile 22 (2002)/Mile.22.mkv", ToDisk: "d2", ToRelPath: "movies/Mile 22 (2002)/Mile.22.mkv"}}, d.Copies)
+	})
+}
@@ -2,108 +2,258 @@ package snapraid
 
 import (
 	"fmt"
-	"strconv"
+	"regexp"
+	"slices"
 	"strings"
 )
 
+var (
+	// missingDiskPattern matches "All the files previously present in disk 'd1' at dir '/mnt/disk1/'".
+	missingDiskPattern = regexp.MustCompile(`(?i)all the files previously present in disk '([^']*)' at dir '([^']*)'`)
+	// emptyDiskPattern matches "The disk 'd1' at dir '/mnt/disk1/' is empty".
+	emptyDiskPattern = regexp.MustCompile(`(?i)disk '([^']*)' at dir '([^']*)' is empty`)
+)
+
+// WarningKind classifies a warning printed by `snapraid diff`.
+type WarningKind string
+
+const (
+	WarningMissingDisk WarningKind = "missing_disk" // all files of a disk are gone, e.g. because it is not mounted
+	WarningEmptyDisk   WarningKind = "empty_disk"   // a disk that used to contain files is empty
+	WarningOther       WarningKind = "other"        // any other "WARNING!" line
+)
+
+// DiffWarning is a warning printed by `snapraid diff`.
+type DiffWarning struct {
+	Kind    WarningKind `json:"kind"`           // classification of the warning
+	Disk    string      `json:"disk,omitempty"` // affected snapraid disk name, if known
+	Dir     string      `json:"dir,omitempty"`  // mount point of the affected disk, if known
+	Message string      `json:"message"`        // warning line as printed by snapraid
+}
+
+// Blocking returns true if the warning indicates a disk that is likely not mounted.
+func (w DiffWarning) Blocking() bool {
+	return w.Kind == WarningMissingDisk || w.Kind == WarningEmptyDisk
+}
+
 // DiffResult holds parsed SnapRAID diff summary and file paths for each change type.
 type DiffResult struct {
-	Equal    int      `json:"equal"`                    // number of files that were equal
-	Added    []string `json:"added_files,omitempty"`    // list of paths for newly added files
-	Removed  []string `json:"removed_files,omitempty"`  // list of paths for removed files
-	Updated  []string `json:"updated_files,omitempty"`  // list of paths for updated files
-	Moved    []string `json:"moved_files,omitempty"`    // list of paths for moved files
-	Copied   []string `json:"copied_files,omitempty"`   // list of paths for copied files
-	Restored []string `json:"restored_files,omitempty"` // list of paths for restored files
+	Equal     int            `json:"equal"`                    // number of files that were equal
+	Added     []string       `json:"added_files,omitempty"`    // list of paths for newly added files
+	Removed   []string       `json:"removed_files,omitempty"`  // list of paths for removed files
+	Updated   []string       `json:"updated_files,omitempty"`  // list of paths for updated files
+	Moved     []string       `json:"moved_files,omitempty"`    // list of paths for moved files
+	Copied    []string       `json:"copied_files,omitempty"`   // list of paths for copied files
+	Restored  []string       `json:"restored_files,omitempty"` // list of paths for restored files
+	Warnings  []DiffWarning  `json:"warnings,omitempty"`       // warnings such as missing or empty disks
+	Entries   []DiffEntry    `json:"entries,omitempty"`        // every change attributed to its data disk
+	Moves     []Transfer     `json:"moves,omitempty"`          // source and destination of every moved file
+	Copies    []Transfer     `json:"copies,omitempty"`         // source and destination of every copied file
+	Reported  map[string]int `json:"reported,omitempty"`       // summary counters printed by snapraid per change category
+	Omitted   map[string]int `json:"omitted,omitempty"`        // entries per change category counted but not listed because of the path limit
+	SpillFile string         `json:"spill_file,omitempty"`     // file listing every entry, if the diff was spilled to disk
+}
+
+// Transfer is a moved or copied file with its old and new location.
+// Paths are as reported by snapraid: relative to the disk from the tag log, absolute from the text output.
+type Transfer struct {
+	From        string `json:"from"`                   // old location, or the source of a copy
+	To          string `json:"to"`                     // new location
+	FromDisk    string `json:"from_disk,omitempty"`    // data disk of From, if known
+	ToDisk      string `json:"to_disk,omitempty"`      // data disk of To, if known
+	FromRelPath string `json:"from_relpath,omitempty"` // From relative to the mount point of its disk, if known
+	ToRelPath   string `json:"to_relpath,omitempty"`   // To relative to the mount point of its disk, if known
+}
+
+// String formats the transfer as "from -> to", naming the disks if they differ.
+func (t Transfer) String() string {
+	if t.FromDisk != t.ToDisk {
+		return fmt.Sprintf("%s (%s) -> %s (%s)", t.From, t.FromDisk, t.To, t.ToDisk)
+	}
+	return t.From + " -> " + t.To
+}
+
+// DiffEntry is a single changed file, attributed to the data disk it is on.
+// Moves and copies describe the new location.
+type DiffEntry struct {
+	Change     string `json:"change"`               // change category: "added", "removed", "updated", "moved", "copied" or "restored"
+	Disk       string `json:"disk,omitempty"`       // snapraid data disk name, if known
+	RelPath    string `json:"relpath,omitempty"`    // path relative to the disk's mount point, if known
+	AbsPath    string `json:"abspath,omitempty"`    // absolute filesystem path, if known
+	Unresolved bool   `json:"unresolved,omitempty"` // the entry could not be attributed to a declared data disk
 }
 
 // HasChanges returns true if any files were added, removed, updated, moved, copied, or restored.
 func (d DiffResult) HasChanges() bool {
-	return len(d.Added) > 0 ||
-		len(d.Removed) > 0 ||
-		len(d.Updated) > 0 ||
-		len(d.Moved) > 0 ||
-		len(d.Copied) > 0 ||
-		len(d.Restored) > 0
-}
-
-// parseDiff processes each line of `snapraid diff`. It recognizes:
-//   - "<number> equal" (possibly indented), to accumulate into d.Equal.
-//   - "add <path>", "remove <path>", etc., splitting on the first space so that
-//     <path> may contain backslashes, parentheses, or spaces (they remain intact).
-func parseDiff(lines []string) DiffResult {
-	var res DiffResult
+	return d.Changes() > 0
+}
 
-	for _, raw := range lines {
-		// Trim leading/trailing whitespace
-		line := strings.TrimSpace(raw)
-		if line == "" {
-			continue
+// Changes returns the total number of added, removed, updated, moved, copied and restored entries.
+func (d DiffResult) Changes() int {
+	n := 0
+	for _, c := range d.counts() {
+		n += c
+	}
+	return n
+}
+
+// Count returns the number of changed files in a category ("added", "removed", ...),
+// including entries omitted from the list because of the path limit.
+func (d DiffResult) Count(category string) int {
+	if i := slices.Index(changeCategories, category); i >= 0 {
+		return d.counts()[i]
+	}
+	return 0
+}
+
+// Truncated returns true if some entries were only counted and not listed.
+func (d DiffResult) Truncated() bool {
+	return len(d.Omitted) > 0
+}
+
+// BlockingWarnings returns the warnings that must prevent a sync.
+func (d DiffResult) BlockingWarnings() []DiffWarning {
+	var blocking []DiffWarning
+	for _, w := range d.Warnings {
+		if w.Blocking() {
+			blocking = append(blocking, w)
 		}
+	}
+	return blocking
+}
 
-		// Check for "<number> equal" summary (two fields exactly)
-		parts := strings.Fields(line)
-		if len(parts) == 2 {
-			if count, err := strconv.Atoi(parts[0]); err == nil {
-				if strings.ToLower(parts[1]) == "equal" {
-					res.Equal += count
-					continue
-				}
-			}
+// parseDiff parses the human-readable output of `snapraid diff`, see diffBuilder.line.
+func parseDiff(lines []string) DiffResult {
+	b := &diffBuilder{}
+	for _, line := range lines {
+		b.line(line)
+	}
+	return b.res
+}
+
+// report records the summary counter snapraid printed for a change category.
+func (d *DiffResult) report(category string, count int) {
+	if d.Reported == nil {
+		d.Reported = make(map[string]int, len(changeCategories))
+	}
+	d.Reported[category] += count
+}
+
+// verifyCounts compares the parsed entries of every category with the counter snapraid
+// reported for it and returns a ParseIntegrityError listing all mismatches, or nil.
+// Categories without a reported counter are not checked.
+func (d DiffResult) verifyCounts() error {
+	var mismatches ParseIntegrityError
+	for i, parsed := range d.counts() {
+		category := changeCategories[i]
+		if reported, ok := d.Reported[category]; ok && reported != parsed {
+			mismatches = append(mismatches, CountMismatch{Category: category, Reported: reported, Parsed: parsed})
 		}
+	}
+	if len(mismatches) > 0 {
+		return mismatches
+	}
+	return nil
+}
 
-		// Otherwise, split at the first space to separate action from path
-		// e.g. "add /XOXO\ \(2016\)/... or
-		//      "remove filme/Zoolander\ \(2001\)/..."
-		idx := strings.IndexRune(line, ' ')
-		if idx < 0 {
-			// no space ⇒ not an action/path line
-			continue
+// unescapeShell reverses the shell escaping snapraid applies to paths in its text output,
+// e.g. "Gladiator\ \(2000\)" becomes "Gladiator (2000)".
+func unescapeShell(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
 		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
 
-		action := strings.ToLower(line[:idx])   // "add", "remove", etc.
-		path := strings.TrimSpace(line[idx+1:]) // the rest of the line, including any escaped spaces
-
-		switch action {
-		case "add":
-			res.Added = append(res.Added, path)
-		case "remove":
-			res.Removed = append(res.Removed, path)
-		case "update":
-			res.Updated = append(res.Updated, path)
-		case "move":
-			res.Moved = append(res.Moved, path)
-		case "copy":
-			res.Copied = append(res.Copied, path)
-		case "restore":
-			res.Restored = append(res.Restored, path)
-		default:
-			// unrecognized action ⇒ ignore
+// parseDiffWarnings extracts warnings from diff output, see parseDiffWarning.
+func parseDiffWarnings(lines []string) []DiffWarning {
+	var warnings []DiffWarning
+	for _, raw := range lines {
+		if w, ok := parseDiffWarning(strings.TrimSpace(raw)); ok {
+			warnings = append(warnings, w)
 		}
 	}
+	return warnings
+}
 
-	return res
+// parseDiffWarning classifies a warning line. snapraid prints the missing-disk warning
+// across several lines, so only the line naming the disk is recorded.
+func parseDiffWarning(line string) (DiffWarning, bool) {
+	if m := missingDiskPattern.FindStringSubmatch(line); m != nil {
+		return DiffWarning{Kind: WarningMissingDisk, Disk: m[1], Dir: m[2], Message: line}, true
+	}
+	if m := emptyDiskPattern.FindStringSubmatch(line); m != nil {
+		return DiffWarning{Kind: WarningEmptyDisk, Disk: m[1], Dir: m[2], Message: line}, true
+	}
+	if strings.HasPrefix(line, "WARNING!") {
+		return DiffWarning{Kind: WarningOther, Message: line}, true
+	}
+	return DiffWarning{}, false
 }
 
-// validateThresholds checks if the given diff result violates thresholds.
-func validateThresholds(result DiffResult, t Thresholds) error {
-	if t.Add >= 0 && len(result.Added) > t.Add {
-		return fmt.Errorf("added files exceed threshold (%d > %d)", len(result.Added), t.Add)
+// ArraySize returns the number of files snapraid knew before this diff:
+// unchanged files plus those that were removed, updated, moved or restored.
+func (d DiffResult) ArraySize() int {
+	return d.Equal + d.Count("removed") + d.Count("updated") + d.Count("moved") + d.Count("restored")
+}
+
+// checkThreshold compares count against the absolute limit and the percentage of size.
+// If both are breached, the stricter limit is reported.
+func checkThreshold(category string, count, limit int, percent float64, size int) (ThresholdViolation, bool) {
+	v := ThresholdViolation{Category: category, Count: count, Limit: -1}
+
+	if limit >= 0 && count > limit {
+		v.Limit = limit
 	}
-	if t.Remove >= 0 && len(result.Removed) > t.Remove {
-		return fmt.Errorf("removed files exceed threshold (%d > %d)", len(result.Removed), t.Remove)
+	if percent > 0 {
+		relative := int(float64(size) * percent / 100)
+		if count > relative && (v.Limit < 0 || relative < v.Limit) {
+			v.Limit = relative
+			v.Percent = percent
+		}
 	}
-	if t.Update >= 0 && len(result.Updated) > t.Update {
-		return fmt.Errorf("updated files exceed threshold (%d > %d)", len(result.Updated), t.Update)
+	return v, v.Limit >= 0
+}
+
+// validateThresholds checks the diff result against the path-scoped rules and the global
+// thresholds and returns a ThresholdViolations error listing all breaches, or nil.
+// Entries claimed by a rule are only checked against that rule's limits.
+// Missing or empty disks always fail, regardless of the numeric thresholds.
+func validateThresholds(result DiffResult, t Thresholds) error {
+	var violations ThresholdViolations
+
+	for _, w := range result.BlockingWarnings() {
+		violations = append(violations, ThresholdViolation{Category: string(w.Kind), Disk: w.Disk, Dir: w.Dir})
 	}
-	if t.Move >= 0 && len(result.Moved) > t.Move {
-		return fmt.Errorf("moved files exceed threshold (%d > %d)", len(result.Moved), t.Move)
+
+	size := result.ArraySize()
+	counts := countByRule(result, t.Rules)
+	check := func(limits Thresholds, counts changeCounts, rule string) {
+		for i, l := range limits.limits() {
+			if v, ok := checkThreshold(changeCategories[i], counts[i], l.count, l.percent, size); ok {
+				v.Rule = rule
+				violations = append(violations, v)
+			}
+		}
 	}
-	if t.Copy >= 0 && len(result.Copied) > t.Copy {
-		return fmt.Errorf("copied files exceed threshold (%d > %d)", len(result.Copied), t.Copy)
+
+	check(t, counts[len(t.Rules)], "")
+	for i, rule := range t.Rules {
+		if !rule.Unlimited {
+			check(rule.Limits, counts[i], rule.Pattern)
+		}
 	}
-	if t.Restore >= 0 && len(result.Restored) > t.Restore {
-		return fmt.Errorf("restored files exceed threshold (%d > %d)", len(result.Restored), t.Restore)
+
+	if len(violations) == 0 {
+		return nil
 	}
-	return nil
+	return violations
 }
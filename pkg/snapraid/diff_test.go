@@ -7,6 +7,22 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestTransferString(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Same disk", func(t *testing.T) {
+		t.Parallel()
+		tr := Transfer{From: "old/a.txt", To: "new/a.txt", FromDisk: "d1", ToDisk: "d1"}
+		assert.Equal(t, "old/a.txt -> new/a.txt", tr.String())
+	})
+
+	t.Run("Across disks", func(t *testing.T) {
+		t.Parallel()
+		tr := Transfer{From: "a.txt", To: "b.txt", FromDisk: "d1", ToDisk: "d2"}
+		assert.Equal(t, "a.txt (d1) -> b.txt (d2)", tr.String())
+	})
+}
+
 func TestHasChanges(t *testing.T) {
 	t.Parallel()
 
@@ -77,27 +93,27 @@ There are differences!
 		assert.Equal(t, 21156, dr.Equal)
 
 		expectedAdded := []string{
-			"movies/Gladiator\\ \\(2000\\)/Gladiator.2000.German.AC3.DL.1080p.BluRay.x264.FuN.mk",
-			"movies/Interstellar\\ \\(2014\\)/Interstellar.2014.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
-			"movies/Judge\\ Dredd\\ \\(1995\\)/Judge.Dredd.1995.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
-			"movies/Predator\\ \\(1987\\)/Predator.1987.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
-			"movies/Zoolander\\ \\(2001\\)/Zoolander.2001.German.AC3.DL.1080p.BluRay.x265-FuN.mkv",
+			"movies/Gladiator (2000)/Gladiator.2000.German.AC3.DL.1080p.BluRay.x264.FuN.mk",
+			"movies/Interstellar (2014)/Interstellar.2014.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/Judge Dredd (1995)/Judge.Dredd.1995.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/Predator (1987)/Predator.1987.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/Zoolander (2001)/Zoolander.2001.German.AC3.DL.1080p.BluRay.x265-FuN.mkv",
 		}
 		expectedRemoved := []string{
-			"movies/XOXO\\ \\(2016\\)/XOXO.2016.German.DL.1080p.WEB.x264.iNTERNAL-BiGiNT.mkv",
-			"movies/Zoolander\\ 2\\ \\(2016\\)/Zoolander.2.2016.German.AC3.DL.1080p.BluRay.x265-FuN.mkv",
+			"movies/XOXO (2016)/XOXO.2016.German.DL.1080p.WEB.x264.iNTERNAL-BiGiNT.mkv",
+			"movies/Zoolander 2 (2016)/Zoolander.2.2016.German.AC3.DL.1080p.BluRay.x265-FuN.mkv",
 		}
 		expectedUpdated := []string{
-			"movies/The\\ Matrix\\ \\(1999\\)/The.Matrix.1999.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/The Matrix (1999)/The.Matrix.1999.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
 		}
 		expectedMoved := []string{
-			"movies/The\\ Shawshank\\ Redemption\\ \\(1994\\)/The.Shawshank.Redemption.1994.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/The Shawshank Redemption (1994)/The.Shawshank.Redemption.1994.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
 		}
 		expectedCopied := []string{
-			"movies/Mile\\ 22\\ \\(2002\\)/Mile.22.2002.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/Mile 22 (2002)/Mile.22.2002.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
 		}
 		expectedRestored := []string{
-			"movies/Crazy, Stupid, Love\\. A\\. Piano\\. \\(2009\\)/Crazy.Stupid.Love.A.Piano.2009.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
+			"movies/Crazy, Stupid, Love. A. Piano. (2009)/Crazy.Stupid.Love.A.Piano.2009.German.AC3.DL.1080p.BluRay.x264.FuN.mkv",
 		}
 
 		assert.Equal(t, 21156, dr.Equal)
@@ -109,6 +125,34 @@ There are differences!
 		assert.Equal(t, expectedRestored, dr.Restored)
 	})
 
+	t.Run("Absolute paths with moves", func(t *testing.T) {
+		t.Parallel()
+
+		lines := []string{
+			`add /mnt/disk1/movies/Gladiator\ \(2000\)/a.mkv`,
+			`move /mnt/disk1/old\ name.txt -> /mnt/disk1/new\ name.txt`,
+			`copy /mnt/disk1/src.txt -> /mnt/disk2/dst.txt`,
+		}
+
+		dr := parseDiff(lines)
+		assert.Equal(t, []string{"/mnt/disk1/movies/Gladiator (2000)/a.mkv"}, dr.Added)
+		assert.Equal(t, []string{"/mnt/disk1/old name.txt -> /mnt/disk1/new name.txt"}, dr.Moved)
+		assert.Equal(t, []string{"/mnt/disk1/src.txt -> /mnt/disk2/dst.txt"}, dr.Copied)
+
+		dr.resolveDisks([]DataDisk{{Name: "d1", Dir: "/mnt/disk1/"}, {Name: "d2", Dir: "/mnt/disk2/"}})
+		assert.Equal(t, []DiffEntry{
+			{Change: "added", Disk: "d1", RelPath: "movies/Gladiator (2000)/a.mkv", AbsPath: "/mnt/disk1/movies/Gladiator (2000)/a.mkv"},
+			{Change: "moved", Disk: "d1", RelPath: "new name.txt", AbsPath: "/mnt/disk1/new name.txt"},
+			{Change: "copied", Disk: "d2", RelPath: "dst.txt", AbsPath: "/mnt/disk2/dst.txt"},
+		}, dr.Entries)
+		assert.Equal(t, []Transfer{
+			{From: "/mnt/disk1/old name.txt", To: "/mnt/disk1/new name.txt", FromDisk: "d1", ToDisk: "d1", FromRelPath: "old name.txt", ToRelPath: "new name.txt"},
+		}, dr.Moves)
+		assert.Equal(t, []Transfer{
+			{From: "/mnt/disk1/src.txt", To: "/mnt/disk2/dst.txt", FromDisk: "d1", ToDisk: "d2", FromRelPath: "src.txt", ToRelPath: "dst.txt"},
+		}, dr.Copies)
+	})
+
 	t.Run("Ignores unmatched lines", func(t *testing.T) {
 		t.Parallel()
 
@@ -167,9 +211,144 @@ There are differences!
 	})
 }
 
+func TestParseDiffWarnings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Missing, empty and other warnings", func(t *testing.T) {
+		t.Parallel()
+
+		lines := []string{
+			"WARNING! All the files previously present in disk 'd1' at dir '/mnt/disk1/'",
+			"are now missing or have been rewritten!",
+			"This could occur when restoring a disk with a backup",
+			"The disk 'd2' at dir '/mnt/disk2/' is empty.",
+			"WARNING! Ignoring mount point '/mnt/disk3/' because it appears to be in a different device",
+			"add file.txt",
+		}
+
+		assert.Equal(t, []DiffWarning{
+			{Kind: WarningMissingDisk, Disk: "d1", Dir: "/mnt/disk1/", Message: lines[0]},
+			{Kind: WarningEmptyDisk, Disk: "d2", Dir: "/mnt/disk2/", Message: lines[3]},
+			{Kind: WarningOther, Message: lines[4]},
+		}, parseDiffWarnings(lines))
+	})
+
+	t.Run("parseDiff keeps warnings", func(t *testing.T) {
+		t.Parallel()
+
+		dr := parseDiff([]string{
+			"All the files previously present in disk 'd1' at dir '/mnt/disk1/'",
+			"remove a.txt",
+		})
+		assert.Len(t, dr.Warnings, 1)
+		assert.Len(t, dr.BlockingWarnings(), 1)
+		assert.Equal(t, []string{"a.txt"}, dr.Removed)
+	})
+
+	t.Run("No warnings", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, parseDiffWarnings([]string{"add a.txt", "10 equal"}))
+	})
+}
+
 func TestValidateThresholds(t *testing.T) {
 	t.Parallel()
 
+	t.Run("Missing disk fails with disabled thresholds", func(t *testing.T) {
+		t.Parallel()
+
+		result := DiffResult{
+			Removed:  []string{"a.txt"},
+			Warnings: []DiffWarning{{Kind: WarningMissingDisk, Disk: "d1", Dir: "/mnt/disk1/"}},
+		}
+		thresholds := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+
+		err := validateThresholds(result, thresholds)
+		assert.EqualError(t, err, "all files on disk d1 at /mnt/disk1/ are missing")
+	})
+
+	t.Run("Empty disk fails", func(t *testing.T) {
+		t.Parallel()
+
+		result := DiffResult{Warnings: []DiffWarning{{Kind: WarningEmptyDisk, Disk: "d2", Dir: "/mnt/disk2/"}}}
+
+		err := validateThresholds(result, Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1})
+		assert.EqualError(t, err, "disk d2 at /mnt/disk2/ is empty")
+	})
+
+	t.Run("Every violation is reported", func(t *testing.T) {
+		t.Parallel()
+
+		result := DiffResult{
+			Removed:  []string{"r1", "r2", "r3"},
+			Updated:  []string{"u1", "u2"},
+			Warnings: []DiffWarning{{Kind: WarningEmptyDisk, Disk: "d2", Dir: "/mnt/disk2/"}},
+		}
+		thresholds := Thresholds{Add: -1, Remove: 1, Update: 1, Move: -1, Copy: -1, Restore: -1}
+
+		err := validateThresholds(result, thresholds)
+		assert.EqualError(t, err, "disk d2 at /mnt/disk2/ is empty; removed files exceed threshold (3 > 1); updated files exceed threshold (2 > 1)")
+
+		var violations ThresholdViolations
+		assert.ErrorAs(t, err, &violations)
+		assert.Equal(t, ThresholdViolations{
+			{Category: "empty_disk", Disk: "d2", Dir: "/mnt/disk2/"},
+			{Category: "removed", Count: 3, Limit: 1},
+			{Category: "updated", Count: 2, Limit: 1},
+		}, violations)
+		assert.Equal(t, ErrorKindThreshold, classifyError(err))
+	})
+
+	t.Run("Percentage threshold relative to array size", func(t *testing.T) {
+		t.Parallel()
+
+		// 990 equal + 10 removed = 1000 known files, 0.5% = 5
+		result := DiffResult{
+			Equal:   990,
+			Removed: []string{"r1", "r2", "r3", "r4", "r5", "r6", "r7", "r8", "r9", "r10"},
+		}
+		thresholds := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1, RemovePercent: 0.5}
+
+		err := validateThresholds(result, thresholds)
+		assert.EqualError(t, err, "removed files exceed threshold (10 > 5, 0.5% of array)")
+
+		thresholds.RemovePercent = 1
+		assert.NoError(t, validateThresholds(result, thresholds))
+	})
+
+	t.Run("Absolute and percentage combined report the stricter limit", func(t *testing.T) {
+		t.Parallel()
+
+		result := DiffResult{
+			Equal:   990,
+			Removed: []string{"r1", "r2", "r3", "r4", "r5", "r6", "r7", "r8", "r9", "r10"},
+		}
+
+		// Absolute limit is stricter
+		err := validateThresholds(result, Thresholds{Add: -1, Remove: 2, Update: -1, Move: -1, Copy: -1, Restore: -1, RemovePercent: 0.5})
+		assert.EqualError(t, err, "removed files exceed threshold (10 > 2)")
+
+		// Percentage is stricter
+		err = validateThresholds(result, Thresholds{Add: -1, Remove: 8, Update: -1, Move: -1, Copy: -1, Restore: -1, RemovePercent: 0.5})
+		var violations ThresholdViolations
+		assert.ErrorAs(t, err, &violations)
+		assert.Equal(t, ThresholdViolations{{Category: "removed", Count: 10, Limit: 5, Percent: 0.5}}, violations)
+
+		// Neither is exceeded
+		err = validateThresholds(result, Thresholds{Add: -1, Remove: 20, Update: -1, Move: -1, Copy: -1, Restore: -1, RemovePercent: 2})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Other warnings do not fail", func(t *testing.T) {
+		t.Parallel()
+
+		result := DiffResult{Warnings: []DiffWarning{{Kind: WarningOther, Message: "WARNING! something"}}}
+
+		err := validateThresholds(result, Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1})
+		assert.NoError(t, err)
+	})
+
 	t.Run("No violation when all counts within thresholds", func(t *testing.T) {
 		t.Parallel()
 
@@ -338,3 +517,40 @@ func TestValidateThresholds(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestVerifyCounts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Parsed counters match", func(t *testing.T) {
+		t.Parallel()
+
+		dr := parseDiff([]string{
+			"add a.txt",
+			"remove b.txt",
+			"   10 equal",
+			"    1 added",
+			"    1 removed",
+			"    0 updated",
+		})
+		assert.Equal(t, map[string]int{"added": 1, "removed": 1, "updated": 0}, dr.Reported)
+		assert.NoError(t, dr.verifyCounts())
+	})
+
+	t.Run("Missed lines are reported", func(t *testing.T) {
+		t.Parallel()
+
+		dr := DiffResult{
+			Added:    []string{"a.txt"},
+			Reported: map[string]int{"added": 2, "removed": 1, "moved": 0},
+		}
+		err := dr.verifyCounts()
+		assert.EqualError(t, err, "diff parse integrity check failed: added: snapraid reported 2, parsed 1; removed: snapraid reported 1, parsed 0")
+		assert.Equal(t, ErrorKindParseIntegrity, classifyError(err))
+	})
+
+	t.Run("Without counters nothing is checked", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, DiffResult{Added: []string{"a.txt"}}.verifyCounts())
+	})
+}
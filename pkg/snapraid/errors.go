@@ -0,0 +1,188 @@
+package snapraid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies why a run stopped before completing all steps.
+type ErrorKind string
+
+const (
+	ErrorKindFailed    ErrorKind = "failed"    // a step returned an error
+	ErrorKindCancelled ErrorKind = "cancelled" // the run was interrupted (signal or context cancellation)
+	ErrorKindTimeout   ErrorKind = "timeout"   // a step or the whole run exceeded its time limit
+	ErrorKindThreshold ErrorKind = "threshold" // sync was blocked by the threshold gate
+
+	ErrorKindParseIntegrity ErrorKind = "parse_integrity" // parsed diff entries do not match snapraid's summary counters
+	ErrorKindZeroSize       ErrorKind = "zero_size"       // sync refused files that were truncated to zero bytes
+	ErrorKindEmptyDisk      ErrorKind = "empty_disk"      // sync refused a disk that is missing all its files
+)
+
+// TimeoutError reports that a step, or the run as a whole, exceeded its time limit.
+// It is used as the cancellation cause of the step context and unwraps to context.DeadlineExceeded.
+type TimeoutError struct {
+	Step  string        // Step is the step whose limit was hit; empty for the total run budget.
+	Limit time.Duration // Limit is the configured time limit.
+}
+
+// Error implements error.
+func (e *TimeoutError) Error() string {
+	if e.Step == "" {
+		return fmt.Sprintf("run exceeded total time budget of %s", e.Limit)
+	}
+	return fmt.Sprintf("%s timed out after %s", e.Step, e.Limit)
+}
+
+// Unwrap allows errors.Is(err, context.DeadlineExceeded).
+func (e *TimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// CommandError is returned when a snapraid command exits unsuccessfully.
+type CommandError struct {
+	Cmd    string // Cmd is the snapraid subcommand (e.g. "sync").
+	Stderr string // Stderr holds everything the command wrote to stderr.
+	Err    error  // Err is the underlying error, usually an *exec.ExitError.
+}
+
+// Error implements error.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("snapraid %s failed: %v\nstderr:\n%s", e.Cmd, e.Err, e.Stderr)
+}
+
+// Unwrap returns the underlying error.
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code of the command, or -1 if it did not exit normally.
+func (e *CommandError) ExitCode() int {
+	var exitErr *exec.ExitError
+	if !errors.As(e.Err, &exitErr) {
+		return -1
+	}
+	return exitErr.ExitCode()
+}
+
+// ThresholdViolation describes a single breach of the threshold gate.
+type ThresholdViolation struct {
+	Category string  `json:"category"`          // change category ("added", "removed", ...) or warning kind ("missing_disk", "empty_disk")
+	Count    int     `json:"count"`             // number of changes in the category
+	Limit    int     `json:"limit"`             // configured limit for the category
+	Percent  float64 `json:"percent,omitempty"` // relative limit in percent of the array; Limit is the resulting count
+	Rule     string  `json:"rule,omitempty"`    // pattern of the threshold rule that fired; empty for the global thresholds
+	Disk     string  `json:"disk,omitempty"`    // affected disk for missing/empty disk violations
+	Dir      string  `json:"dir,omitempty"`     // mount point of the affected disk
+}
+
+// String returns a human-readable description of the violation.
+func (v ThresholdViolation) String() string {
+	switch WarningKind(v.Category) {
+	case WarningMissingDisk:
+		return fmt.Sprintf("all files on disk %s at %s are missing", v.Disk, v.Dir)
+	case WarningEmptyDisk:
+		return fmt.Sprintf("disk %s at %s is empty", v.Disk, v.Dir)
+	}
+	scope := ""
+	if v.Rule != "" {
+		scope = " in " + v.Rule
+	}
+	if v.Percent > 0 {
+		return fmt.Sprintf("%s files%s exceed threshold (%d > %d, %g%% of array)", v.Category, scope, v.Count, v.Limit, v.Percent)
+	}
+	return fmt.Sprintf("%s files%s exceed threshold (%d > %d)", v.Category, scope, v.Count, v.Limit)
+}
+
+// ThresholdViolations is returned by the threshold gate and lists every breach.
+type ThresholdViolations []ThresholdViolation
+
+// Error implements error.
+func (v ThresholdViolations) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, violation := range v {
+		msgs = append(msgs, violation.String())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// CountMismatch is a change category whose parsed entries differ from snapraid's summary counter.
+type CountMismatch struct {
+	Category string `json:"category"` // change category ("added", "removed", ...)
+	Reported int    `json:"reported"` // count printed by snapraid
+	Parsed   int    `json:"parsed"`   // number of entries parsed from the diff
+}
+
+// ParseIntegrityError is returned if the parsed diff does not add up to snapraid's summary
+// counters, e.g. because lines with unusual paths were missed. It lists every mismatch.
+type ParseIntegrityError []CountMismatch
+
+// Error implements error.
+func (e ParseIntegrityError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, m := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: snapraid reported %d, parsed %d", m.Category, m.Reported, m.Parsed))
+	}
+	return "diff parse integrity check failed: " + strings.Join(msgs, "; ")
+}
+
+// maxRefusedFiles is the number of zero-size files named in the message of a SyncRefusalError.
+const maxRefusedFiles = 5
+
+// SyncRefusalError is returned if snapraid refuses to sync because files were truncated to zero
+// bytes or a disk is missing all its files. It unwraps to the *CommandError of the sync.
+type SyncRefusalError struct {
+	SyncRefusal       // SyncRefusal lists the affected files and disks.
+	Err         error // Err is the underlying error, usually a *CommandError.
+}
+
+// Kind returns ErrorKindZeroSize if any file has zero size, ErrorKindEmptyDisk otherwise.
+func (e *SyncRefusalError) Kind() ErrorKind {
+	if len(e.ZeroSize) > 0 {
+		return ErrorKindZeroSize
+	}
+	return ErrorKindEmptyDisk
+}
+
+// Error implements error.
+func (e *SyncRefusalError) Error() string {
+	var msgs []string
+	if n := len(e.ZeroSize); n > 0 {
+		paths := make([]string, 0, min(n, maxRefusedFiles))
+		for _, f := range e.ZeroSize[:min(n, maxRefusedFiles)] {
+			paths = append(paths, f.AbsPath)
+		}
+		if n > maxRefusedFiles {
+			paths = append(paths, fmt.Sprintf("and %d more", n-maxRefusedFiles))
+		}
+		msgs = append(msgs, fmt.Sprintf("%d files have unexpected zero size (%s)", n, strings.Join(paths, ", ")))
+	}
+	for _, w := range e.EmptyDisks {
+		msgs = append(msgs, fmt.Sprintf("all files on disk %s at %s are missing", w.Disk, w.Dir))
+	}
+	return "snapraid refused to sync: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the underlying error.
+func (e *SyncRefusalError) Unwrap() error { return e.Err }
+
+// classifyError maps err to the ErrorKind reported in RunResult.
+func classifyError(err error) ErrorKind {
+	var refusal *SyncRefusalError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindTimeout
+	case errors.As(err, new(ThresholdViolations)):
+		return ErrorKindThreshold
+	case errors.As(err, new(ParseIntegrityError)):
+		return ErrorKindParseIntegrity
+	case errors.As(err, &refusal):
+		return refusal.Kind()
+	default:
+		return ErrorKindFailed
+	}
+}
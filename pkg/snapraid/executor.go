@@ -3,91 +3,312 @@ package snapraid
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // DefaultExecutor is the real implementation of Snapraid that shells out.
 type DefaultExecutor struct {
-	configPath string       // path to YAML config (used by "--conf")
-	binaryPath string       // path to the snapraid executable
-	scrubPlan  int          // percentage (0–100) passed to "scrub"
-	scrubOlder int          // days passed to "scrub --older-than"
-	logger     *slog.Logger // structured logger for per‐line output
+	configPath  string              // path to YAML config (used by "--conf")
+	binaryPath  string              // path to the snapraid executable
+	scrubPlan   string              // percentage (0–100) or keyword passed to "scrub --plan"
+	scrubOlder  int                 // days passed to "scrub --older-than"
+	diffLimit   int                 // maximum number of diff paths kept in memory per category; 0 keeps all
+	spillDir    string              // directory receiving the complete diff path list, if set
+	filters     Filters             // disk and path filters of check and fix
+	quiet       bool                // pass "--quiet" to every command that reports no progress
+	extraArgs   map[string][]string // additional arguments per snapraid command
+	gracePeriod time.Duration       // time a cancelled child gets to exit after SIGINT before it is killed
+	logger      *slog.Logger        // structured logger for per‐line output
+
+	progressInterval time.Duration  // minimum time between two logged progress lines
+	onProgress       func(Progress) // optional callback receiving every progress update
 }
 
 // Touch shells out to `snapraid touch` and logs each line under "touch".
-func (d *DefaultExecutor) Touch() error {
-	return d.runCommand("touch", nil, "touch")
+func (d *DefaultExecutor) Touch(ctx context.Context) error {
+	return d.runCommand(ctx, "touch", nil, "touch")
 }
 
-// Diff shells out to `snapraid diff`, logs under "diff", and returns all stdout lines.
-// Diff shells out to `snapraid diff`, logs under "diff", and returns all stdout lines.
-func (d *DefaultExecutor) Diff() ([]string, error) {
-	var stdout, stderr bytes.Buffer
-	outWriter := io.MultiWriter(&stdout, newLoggerWriter(d.logger, "diff", slog.LevelInfo))
+// Diff shells out to `snapraid diff`, logs under "diff", and returns the parsed result.
+// The result is built from snapraid's tagged log output; if the binary writes no tags
+// (old releases), the human-readable stdout is parsed instead. stdout is kept in a
+// temporary file until then, so only one result is built, keeping at most diffLimit paths
+// per category in memory. Per-file lines are logged at debug level only.
+func (d *DefaultExecutor) Diff(ctx context.Context) (DiffResult, error) {
+	logPath, cleanup, err := newTagLog()
+	if err != nil {
+		return DiffResult{}, err
+	}
+	defer cleanup()
+
+	textFile, err := os.CreateTemp("", "go-snapraid-diff-*.txt")
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("failed to create diff output file: %w", err)
+	}
+	defer os.Remove(textFile.Name()) // nolint:errcheck
+	defer textFile.Close()           // nolint:errcheck
+	text := bufio.NewWriter(textFile)
+
+	var (
+		stderr      bytes.Buffer
+		textErr     error
+		outWarnings []DiffWarning
+	)
+	outWriter := newLineWriter(func(line string) {
+		if textErr == nil {
+			_, textErr = fmt.Fprintln(text, line)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return
+		}
+		level := slog.LevelInfo
+		if w, ok := parseDiffWarning(line); ok {
+			outWarnings = append(outWarnings, w)
+		} else if isChangeLine(line) {
+			level = slog.LevelDebug
+		}
+		d.logger.Log(ctx, level, line, "tag", "diff")
+	})
 	errWriter := io.MultiWriter(&stderr, newLoggerWriter(d.logger, "diff", slog.LevelError))
 
-	err := d.runCommandToWriter("diff", nil, outWriter, errWriter)
+	err = d.runCommandToWriter(ctx, "diff", []string{"--log", logPath}, outWriter, errWriter)
+	outWriter.Flush()
 	if err != nil && !isAcceptableExitCode(err, 0, 2) {
-		return nil, fmt.Errorf("snapraid diff failed: %w\nstderr:\n%s", err, stderr.String())
+		return DiffResult{}, &CommandError{Cmd: "diff", Stderr: stderr.String(), Err: err}
 	}
 
-	var lines []string
-	scanner := bufio.NewScanner(&stdout)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	// Warnings about missing or empty disks are printed as text, usually on stderr
+	warnings := parseDiffWarnings(splitLines(&stderr))
+
+	b, err := newDiffBuilder(d.diffLimit, d.spillDir)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	_, ok, err := readTagLog(logPath, b)
+	if err != nil {
+		b.discard()
+		return DiffResult{}, err
+	}
+	if ok {
+		b.res.Warnings = outWarnings
+	} else {
+		// The builder is still empty, as snapraid wrote no tags
+		if textErr == nil {
+			textErr = text.Flush()
+		}
+		if textErr == nil {
+			textErr = readTextDiff(textFile, b)
+		}
+		if textErr != nil {
+			b.discard()
+			return DiffResult{}, fmt.Errorf("failed to parse diff output: %w", textErr)
+		}
+	}
+	b.res.Warnings = append(b.res.Warnings, warnings...)
+
+	res, err := b.result()
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	// Attribute every entry to its data disk
+	disks, err := loadDataDisks(d.configPath)
+	if err != nil {
+		d.logger.Warn("Cannot attribute diff entries to data disks", "error", err, "tag", "diff")
+	}
+	res.resolveDisks(disks)
+	return res, nil
+}
+
+// Sync shells out to `snapraid sync [force...]`, logs each line under "sync" and returns
+// the summary snapraid reported, or nil if it wrote none. If snapraid refuses to sync
+// zero-size files or empty disks, a *SyncRefusalError lists them.
+func (d *DefaultExecutor) Sync(ctx context.Context, force []string) (*Summary, error) {
+	summary, err := d.runSummaryCommand(ctx, "sync", slices.Clone(force))
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || ctx.Err() != nil {
+		return summary, err
+	}
+	refusal := parseSyncRefusal(splitLines(strings.NewReader(cmdErr.Stderr)))
+	if !refusal.refused() {
+		return summary, err
+	}
+
+	disks, lerr := loadDataDisks(d.configPath)
+	if lerr != nil {
+		d.logger.Warn("Cannot attribute zero-size files to data disks", "error", lerr, "tag", "sync")
+	}
+	refusal.resolve(disks)
+	return summary, &SyncRefusalError{SyncRefusal: refusal, Err: err}
+}
+
+// Scrub shells out to `snapraid scrub --plan X [--older-than Y]` under "scrub" and returns
+// the summary snapraid reported, or nil if it wrote none. A non-empty plan, e.g. "bad",
+// is used together with olderThan instead of the configured ones.
+func (d *DefaultExecutor) Scrub(ctx context.Context, plan string, olderThan int) (*Summary, error) {
+	if plan == "" {
+		plan, olderThan = d.scrubPlan, d.scrubOlder
+	}
+	return d.runSummaryCommand(ctx, "scrub", scrubArgs(plan, olderThan))
+}
+
+// scrubArgs returns the arguments of `snapraid scrub` for plan. "--older-than" only applies
+// to a percentage; the keywords "new", "bad" and "full" select their blocks regardless of age.
+func scrubArgs(plan string, olderThan int) []string {
+	args := []string{"--plan", plan}
+	if _, err := strconv.Atoi(plan); err == nil {
+		args = append(args, "--older-than", strconv.Itoa(olderThan))
 	}
-	return lines, nil
+	return args
+}
+
+// Fix shells out to `snapraid fix -e` under "fix", repairing only the blocks marked bad,
+// and returns the summary snapraid reported, or nil if it wrote none.
+func (d *DefaultExecutor) Fix(ctx context.Context) (*Summary, error) {
+	return d.runSummaryCommand(ctx, "fix", []string{"-e"})
 }
 
-// Sync shells out to `snapraid sync` and logs each line under "sync".
-func (d *DefaultExecutor) Sync() error {
-	return d.runCommand("sync", nil, "sync")
+// Check shells out to `snapraid check -e` under "check", verifying only the blocks marked bad
+// without changing anything, and returns the summary snapraid reported, or nil if it wrote none.
+func (d *DefaultExecutor) Check(ctx context.Context) (*Summary, error) {
+	return d.runSummaryCommand(ctx, "check", []string{"-e"})
 }
 
-// Scrub shells out to `snapraid scrub --plan X --older-than Y` under "scrub".
-func (d *DefaultExecutor) Scrub() error {
-	args := []string{
-		"--plan", strconv.Itoa(d.scrubPlan),
-		"--older-than", strconv.Itoa(d.scrubOlder),
+// Smart shells out to `snapraid smart`, logs each line under "smart" and returns
+// the parsed disk report, or nil if the output contained no disk table.
+func (d *DefaultExecutor) Smart(ctx context.Context) (*SmartReport, error) {
+	out, err := d.runCommandOutput(ctx, "smart", nil, "smart")
+	if err != nil {
+		return nil, err
 	}
-	return d.runCommand("scrub", args, "scrub")
+	return parseSmart(strings.Split(out, "\n")), nil
 }
 
-// Smart shells out to `snapraid smart` and logs each line under "smart".
-func (d *DefaultExecutor) Smart() error {
-	return d.runCommand("smart", nil, "smart")
+// Status shells out to `snapraid status`, logs each line under "status" and returns
+// the parsed array status, or nil if the output contained no disk table.
+func (d *DefaultExecutor) Status(ctx context.Context) (*StatusReport, error) {
+	out, err := d.runCommandOutput(ctx, "status", nil, "status")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(strings.Split(out, "\n")), nil
+}
+
+// runSummaryCommand runs `snapraid <cmd> [args...] [filters...] --log <tmp>` and parses the summary tags.
+// The summary is returned even if the command failed, so error counters are not lost.
+func (d *DefaultExecutor) runSummaryCommand(ctx context.Context, cmd string, args []string) (*Summary, error) {
+	filter, err := d.filterArgs(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	logPath, cleanup, err := newTagLog()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args = append(append(args, filter...), "--log", logPath)
+	err = d.runCommand(ctx, cmd, args, cmd)
+
+	// Only the summary is needed; scan tags of a sync are skipped instead of collected
+	rep, ok, logErr := readTagLog(logPath, nil)
+	if err == nil {
+		err = logErr
+	}
+	if ok {
+		return &rep.Summary, err
+	}
+	return nil, err
 }
 
 // runCommand runs `snapraid <cmd> [args...]`, logging under the given tag.
-func (d *DefaultExecutor) runCommand(cmd string, args []string, tag string) error {
+func (d *DefaultExecutor) runCommand(ctx context.Context, cmd string, args []string, tag string) error {
+	_, err := d.runCommandOutput(ctx, cmd, args, tag)
+	return err
+}
+
+// runCommandOutput runs `snapraid <cmd> [args...]`, logging under the given tag, and returns stdout.
+func (d *DefaultExecutor) runCommandOutput(ctx context.Context, cmd string, args []string, tag string) (string, error) {
 	var outBuf, errBuf bytes.Buffer
 
-	stdoutLog := newLoggerWriter(d.logger, tag, slog.LevelInfo)
+	stdoutLog := newLoggerWriter(d.logger, tag, slog.LevelInfo).
+		withProgress(newProgressTracker(tag, d.progressInterval, d.onProgress, d.logger))
 	stderrLog := newLoggerWriter(d.logger, tag, slog.LevelError)
 
 	stdoutCombined := io.MultiWriter(&outBuf, stdoutLog)
 	stderrCombined := io.MultiWriter(&errBuf, stderrLog)
 
-	err := d.runCommandToWriter(cmd, args, stdoutCombined, stderrCombined)
+	err := d.runCommandToWriter(ctx, cmd, args, stdoutCombined, stderrCombined)
 	if err != nil {
-		return fmt.Errorf("snapraid %s failed: %w\nstderr:\n%s", cmd, err, errBuf.String())
+		return outBuf.String(), &CommandError{Cmd: cmd, Stderr: errBuf.String(), Err: err}
 	}
-	return nil
+	return outBuf.String(), nil
 }
 
-// runCommandToWriter builds and invokes the actual `snapraid <cmd> …`, writing stdout+stderr to w.
-func (d *DefaultExecutor) runCommandToWriter(cmd string, args []string, stdout, stderr io.Writer) error {
-	baseArgs := []string{"--conf", d.configPath, "--quiet"}
-	fullArgs := append([]string{cmd}, append(baseArgs, args...)...)
+// progressCommands report a progress line that "--quiet" would suppress, so they never get it.
+var progressCommands = []string{"sync", "scrub", "check", "fix"}
+
+// runCommandToWriter builds and invokes `snapraid <cmd> --conf <path> [--quiet] [extra args...] [args...]`,
+// writing stdout+stderr to w.
+// When ctx is cancelled the child receives SIGINT so snapraid can save its state; if it is still
+// running after the grace period (or no grace period is set) it is killed. The returned error
+// then wraps the cause of the cancellation (context.Canceled or a *TimeoutError).
+func (d *DefaultExecutor) runCommandToWriter(ctx context.Context, cmd string, args []string, stdout, stderr io.Writer) error {
+	fullArgs := []string{cmd, "--conf", d.configPath}
+	if d.quiet && !slices.Contains(progressCommands, cmd) {
+		fullArgs = append(fullArgs, "--quiet")
+	}
+	fullArgs = append(fullArgs, d.extraArgs[cmd]...)
+	fullArgs = append(fullArgs, args...)
 
 	fmt.Fprintf(stdout, "Running %s\n", cmd) // nolint:errcheck
-	c := exec.Command(d.binaryPath, fullArgs...)
+	c := exec.CommandContext(ctx, d.binaryPath, fullArgs...)
 	c.Stdout = stdout
 	c.Stderr = stderr
-	return c.Run()
+	if d.gracePeriod > 0 {
+		c.Cancel = func() error { return c.Process.Signal(os.Interrupt) }
+		c.WaitDelay = d.gracePeriod
+	}
+
+	err := c.Run()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", context.Cause(ctx), err)
+	}
+	return err
+}
+
+// splitLines reads all lines from r.
+func splitLines(r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// newTagLog creates an empty temporary file for snapraid's tagged log output.
+// The returned cleanup function removes it.
+func newTagLog() (string, func(), error) {
+	f, err := os.CreateTemp("", "go-snapraid-*.log")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create snapraid log file: %w", err)
+	}
+	path := f.Name()
+	f.Close()                                    // nolint:errcheck
+	return path, func() { os.Remove(path) }, nil // nolint:errcheck
 }
@@ -1,9 +1,13 @@
 package snapraid
 
 import (
+	"context"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gi8lino/go-snapraid/internal/testutils"
 
@@ -23,11 +27,11 @@ func TestDefaultExecutor_Touch(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnZero,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Touch()
+		err := ex.Touch(context.Background())
 		assert.NoError(t, err)
 	})
 
@@ -36,11 +40,11 @@ func TestDefaultExecutor_Touch(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnOne,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Touch()
+		err := ex.Touch(context.Background())
 		assert.Error(t, err)
 	})
 }
@@ -57,11 +61,11 @@ func TestDefaultExecutor_Sync(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnZero,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Sync()
+		_, err := ex.Sync(context.Background(), nil)
 		assert.NoError(t, err)
 	})
 
@@ -70,13 +74,53 @@ func TestDefaultExecutor_Sync(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnOne,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
 
-		err := ex.Sync()
+		_, err := ex.Sync(context.Background(), nil)
 		assert.Error(t, err)
+		assert.Equal(t, ErrorKindFailed, classifyError(err))
+	})
+
+	t.Run("Sync refuses zero-size files", func(t *testing.T) {
+		t.Parallel()
+
+		conf := filepath.Join(t.TempDir(), "snapraid.conf")
+		assert.NoError(t, os.WriteFile(conf, []byte("data d1 /mnt/d1/\n"), 0o600))
+
+		script := `echo "The file '/mnt/d1/app/db.lock' has unexpected zero size!" >&2
+echo "If this an expected state you can 'sync' anyway using 'snapraid --force-zero sync'" >&2`
+		ex := &DefaultExecutor{
+			configPath: conf,
+			binaryPath: testutils.WriteScriptFile(t, script, 1),
+			logger:     logger,
+		}
+
+		_, err := ex.Sync(context.Background(), nil)
+
+		var refusal *SyncRefusalError
+		assert.ErrorAs(t, err, &refusal)
+		assert.Equal(t, []ZeroSizeFile{{Disk: "d1", RelPath: "app/db.lock", AbsPath: "/mnt/d1/app/db.lock"}}, refusal.ZeroSize)
+		assert.Equal(t, ErrorKindZeroSize, classifyError(err))
+		assert.ErrorAs(t, err, new(*CommandError))
+	})
+
+	t.Run("Sync passes force options", func(t *testing.T) {
+		t.Parallel()
+
+		// Fails unless the force options are passed before the tag log
+		script := `case "$*" in "sync --conf dummy.conf --force-zero --force-empty --log "*) exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			logger:     logger,
+		}
+
+		_, err := ex.Sync(context.Background(), []string{ForceZero, ForceEmpty})
+		assert.NoError(t, err)
 	})
 }
 
@@ -91,12 +135,26 @@ func TestDefaultExecutor_Smart(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnZero,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Smart()
+		_, err := ex.Smart(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("Smart returns parsed report", func(t *testing.T) {
+		t.Parallel()
+		script := "cat <<'EOF'\n" + smartOutput + "EOF"
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			logger:     logger,
+		}
+		rep, err := ex.Smart(context.Background())
 		assert.NoError(t, err)
+		assert.Len(t, rep.Disks, 4)
+		assert.Equal(t, 64, *rep.FailureProbability)
 	})
 
 	t.Run("Smart returns error", func(t *testing.T) {
@@ -104,11 +162,11 @@ func TestDefaultExecutor_Smart(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnOne,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Smart()
+		_, err := ex.Smart(context.Background())
 		assert.Error(t, err)
 	})
 }
@@ -124,11 +182,11 @@ func TestDefaultExecutor_Scrub(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnZero,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Scrub()
+		_, err := ex.Scrub(context.Background(), "", 0)
 		assert.NoError(t, err)
 	})
 
@@ -137,11 +195,11 @@ func TestDefaultExecutor_Scrub(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: returnOne,
-			scrubPlan:  5,
+			scrubPlan:  "5",
 			scrubOlder: 10,
 			logger:     logger,
 		}
-		err := ex.Scrub()
+		_, err := ex.Scrub(context.Background(), "", 0)
 		assert.Error(t, err)
 	})
 }
@@ -155,15 +213,15 @@ func TestDefaultExecutor_Diff(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: testutils.WriteScriptFile(t, "echo line1\necho line2", 0),
-			scrubPlan:  0,
+			scrubPlan:  "0",
 			scrubOlder: 0,
 			logger:     logger,
 		}
 
-		lines, err := ex.Diff()
+		result, err := ex.Diff(context.Background())
 		assert.NoError(t, err)
-		// The first line printed by runCommandToWriter is "Running diff"
-		assert.Equal(t, []string{"Running diff", "line1", "line2"}, lines)
+		// Lines that are neither entries nor counters are ignored
+		assert.Equal(t, DiffResult{}, result)
 	})
 
 	t.Run("Diff returns exit code 2 (diff)", func(t *testing.T) {
@@ -171,16 +229,16 @@ func TestDefaultExecutor_Diff(t *testing.T) {
 
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
-			binaryPath: testutils.WriteScriptFile(t, "echo ignored", 2),
-			scrubPlan:  0,
+			binaryPath: testutils.WriteScriptFile(t, "echo 'add file.txt'\necho '3 equal'", 2),
+			scrubPlan:  "0",
 			scrubOlder: 0,
 			logger:     logger,
 		}
 
-		lines, err := ex.Diff()
+		result, err := ex.Diff(context.Background())
 		assert.NoError(t, err)
-		assert.Equal(t, "Running diff", lines[0])
-		assert.Equal(t, "ignored", lines[1])
+		assert.Equal(t, 3, result.Equal)
+		assert.Equal(t, []string{"file.txt"}, result.Added)
 	})
 
 	t.Run("Diff returns exit code 3 (diff)", func(t *testing.T) {
@@ -190,13 +248,439 @@ func TestDefaultExecutor_Diff(t *testing.T) {
 		ex := &DefaultExecutor{
 			configPath: "dummy.conf",
 			binaryPath: testutils.WriteScriptFile(t, "echo fail", 3),
-			scrubPlan:  0,
+			scrubPlan:  "0",
 			scrubOlder: 0,
 			logger:     logger,
 		}
 
-		lines, err := ex.Diff()
+		result, err := ex.Diff(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, DiffResult{}, result)
+	})
+}
+
+// tagScript returns a script body that writes tags to the file passed via --log.
+func tagScript(tags string) string {
+	return `while [ $# -gt 0 ]; do
+  if [ "$1" = "--log" ]; then log="$2"; fi
+  shift
+done
+printf '` + tags + `' > "$log"`
+}
+
+func TestDefaultExecutor_Tags(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+
+	t.Run("Diff prefers tagged output", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `scan:add:d1:movies/Gladiator (2000)/a\\db.mkv\nscan:remove:d2:old.txt\nsummary:equal:42\nsummary:exit:diff\n`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, tagScript(tags)+"\necho 'add ignored.txt'", 2),
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 42, result.Equal)
+		assert.Equal(t, []string{"movies/Gladiator (2000)/a:b.mkv"}, result.Added)
+		assert.Equal(t, []string{"old.txt"}, result.Removed)
+	})
+
+	t.Run("Diff attributes entries to data disks", func(t *testing.T) {
+		t.Parallel()
+
+		conf := testutils.WriteFile(t, "parity /mnt/parity/snapraid.parity\ndata d1 /mnt/disk1/\ndata d2 /mnt/disk2/\n")
+		tags := `scan:add:d1:movies/a.mkv\nsummary:exit:diff\n`
+		ex := &DefaultExecutor{
+			configPath: conf,
+			binaryPath: testutils.WriteScriptFile(t, tagScript(tags), 2),
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []DiffEntry{
+			{Change: "added", Disk: "d1", RelPath: "movies/a.mkv", AbsPath: "/mnt/disk1/movies/a.mkv"},
+		}, result.Entries)
+	})
+
+	t.Run("Diff parses warnings from stderr", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `scan:remove:d1:a.txt\nsummary:exit:diff\n`
+		script := tagScript(tags) + "\necho \"WARNING! All the files previously present in disk 'd1' at dir '/mnt/disk1/'\" >&2"
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 2),
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.txt"}, result.Removed)
+		assert.Len(t, result.BlockingWarnings(), 1)
+		assert.Equal(t, "d1", result.Warnings[0].Disk)
+	})
+
+	t.Run("Diff caps the path list and spills every entry", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		tags := `scan:add:d1:a.txt\nscan:add:d1:b.txt\nscan:add:d1:c.txt\nsummary:added:3\nsummary:exit:diff\n`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, tagScript(tags), 2),
+			diffLimit:  1,
+			spillDir:   dir,
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.txt"}, result.Added)
+		assert.Equal(t, 3, result.Count("added"))
+		assert.NoError(t, result.verifyCounts())
+
+		raw, err := os.ReadFile(result.SpillFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "added\ta.txt\nadded\tb.txt\nadded\tc.txt\n", string(raw))
+
+		// Only the spill file of the tag log is kept
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Diff without tags parses stdout with the same limits", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		script := "echo 'add a.txt'\necho 'add b.txt'\necho \"WARNING! All the files previously present in disk 'd1' at dir '/mnt/disk1/'\"\necho '2 added'"
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 2),
+			diffLimit:  1,
+			spillDir:   dir,
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.txt"}, result.Added)
+		assert.Equal(t, 2, result.Count("added"))
+		assert.Len(t, result.BlockingWarnings(), 1)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Diff with tags keeps warnings from stdout", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `scan:remove:d1:a.txt\nsummary:exit:diff\n`
+		script := tagScript(tags) + "\necho \"WARNING! All the files previously present in disk 'd1' at dir '/mnt/disk1/'\""
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 2),
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.txt"}, result.Removed)
+		assert.Len(t, result.BlockingWarnings(), 1)
+	})
+
+	t.Run("Diff logs per-file lines at debug level", func(t *testing.T) {
+		t.Parallel()
+
+		var buf strings.Builder
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, "echo 'add secret.txt'\necho '1 added'", 2),
+			logger:     logger,
+		}
+
+		result, err := ex.Diff(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"secret.txt"}, result.Added)
+		assert.NotContains(t, buf.String(), "secret.txt")
+		assert.Contains(t, buf.String(), "1 added")
+	})
+
+	t.Run("Scrub returns summary even on failure", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `summary:error_file:0\nsummary:error_io:1\nsummary:error_data:3\nsummary:exit:error\n`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, tagScript(tags), 1),
+			logger:     logger,
+		}
+
+		summary, err := ex.Scrub(context.Background(), "", 0)
 		assert.Error(t, err)
-		assert.Nil(t, lines)
+		assert.Equal(t, &Summary{Exit: "error", IOErrors: 1, DataErrors: 3}, summary)
+	})
+
+	t.Run("Scrub with a plan keyword", func(t *testing.T) {
+		t.Parallel()
+
+		// Fails unless called with "--plan bad" and without "--older-than"
+		script := `case "$*" in *--older-than*) exit 1 ;; *"--plan bad"*) exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			scrubPlan:  "5",
+			scrubOlder: 10,
+			logger:     logger,
+		}
+
+		_, err := ex.Scrub(context.Background(), "bad", 0)
+		assert.NoError(t, err)
 	})
+
+	t.Run("Scrub with a planned percentage", func(t *testing.T) {
+		t.Parallel()
+
+		// Fails unless called with the given plan instead of the configured one
+		script := `case "$*" in *"--plan 14 --older-than 3"*) exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			scrubPlan:  "5",
+			scrubOlder: 10,
+			logger:     logger,
+		}
+
+		_, err := ex.Scrub(context.Background(), "14", 3)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fix with a disk filter", func(t *testing.T) {
+		t.Parallel()
+
+		conf := filepath.Join(t.TempDir(), "snapraid.conf")
+		assert.NoError(t, os.WriteFile(conf, []byte("data d1 /mnt/d1/\ndata d2 /mnt/d2/\n"), 0o600))
+
+		// Fails unless the filter is passed before the tag log
+		script := `case "$*" in *"-e --filter-disk d2 --log "*) exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: conf,
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			filters:    Filters{Fix: Filter{Disks: []string{"d2"}}},
+			logger:     logger,
+		}
+
+		_, err := ex.Fix(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("Scrub reports progress despite quiet", func(t *testing.T) {
+		t.Parallel()
+
+		// Prints progress only without --quiet, like snapraid
+		script := `case "$*" in *--quiet*) exit 0 ;; esac
+printf "47%%, 123 MB, 10 MB/s, CPU 5%%, 0:10 ETA\n"`
+		var got []Progress
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			scrubPlan:  "5",
+			scrubOlder: 10,
+			quiet:      true,
+			logger:     logger,
+			onProgress: func(p Progress) { got = append(got, p) },
+		}
+
+		_, err := ex.Scrub(context.Background(), "", 0)
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, 47, got[0].Percent)
+	})
+
+	t.Run("Touch is quiet", func(t *testing.T) {
+		t.Parallel()
+
+		script := `case "$*" in "touch --conf dummy.conf --quiet") exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			quiet:      true,
+			logger:     logger,
+		}
+
+		assert.NoError(t, ex.Touch(context.Background()))
+	})
+
+	t.Run("Scrub with extra arguments and without quiet", func(t *testing.T) {
+		t.Parallel()
+
+		// Fails if --quiet is passed or the extra arguments are missing
+		script := `case "$*" in *--quiet*) exit 1 ;; "scrub --conf dummy.conf -v --test-io-cache=8 --plan 5 "*) exit 0 ;; esac
+exit 1`
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			scrubPlan:  "5",
+			scrubOlder: 10,
+			extraArgs:  map[string][]string{"scrub": {"-v", "--test-io-cache=8"}, "sync": {"-h"}},
+			logger:     logger,
+		}
+
+		_, err := ex.Scrub(context.Background(), "", 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fix returns recovered blocks", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `summary:error_file:0\nsummary:error_io:0\nsummary:error_data:3\nsummary:error_recovered:2\nsummary:error_unrecoverable:1\nsummary:exit:error\n`
+		script := `case "$*" in "fix "*" -e "*) ;; *) exit 3 ;; esac
+` + tagScript(tags)
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 1),
+			logger:     logger,
+		}
+
+		summary, err := ex.Fix(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, &Summary{Exit: "error", DataErrors: 3, Recovered: 2, Unrecoverable: 1}, summary)
+	})
+
+	t.Run("Check returns its summary", func(t *testing.T) {
+		t.Parallel()
+
+		tags := `summary:error_data:0\nsummary:exit:ok\n`
+		script := `case "$*" in "check "*" -e "*) ;; *) exit 3 ;; esac
+` + tagScript(tags)
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			logger:     logger,
+		}
+
+		summary, err := ex.Check(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &Summary{Exit: "ok"}, summary)
+	})
+
+	t.Run("Sync without tags returns no summary", func(t *testing.T) {
+		t.Parallel()
+
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, "echo done", 0),
+			logger:     logger,
+		}
+
+		summary, err := ex.Sync(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Nil(t, summary)
+	})
+}
+
+func TestDefaultExecutor_Cancel(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+
+	t.Run("Cancelled context interrupts the child", func(t *testing.T) {
+		t.Parallel()
+
+		// The script exits cleanly on SIGINT, like snapraid after saving its state.
+		script := "trap 'echo interrupted; exit 130' INT\nwhile true; do sleep 0.05; done"
+		ex := &DefaultExecutor{
+			configPath:  "dummy.conf",
+			binaryPath:  testutils.WriteScriptFile(t, script, 0),
+			gracePeriod: 5 * time.Second,
+			logger:      logger,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := ex.Sync(ctx, nil)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 5*time.Second, "child should exit before the grace period ends")
+	})
+
+	t.Run("Child ignoring SIGINT is killed after grace period", func(t *testing.T) {
+		t.Parallel()
+
+		script := "trap '' INT\nwhile true; do sleep 0.05; done"
+		ex := &DefaultExecutor{
+			configPath:  "dummy.conf",
+			binaryPath:  testutils.WriteScriptFile(t, script, 0),
+			gracePeriod: 200 * time.Millisecond,
+			logger:      logger,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		_, err := ex.Smart(ctx)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestDefaultExecutor_Status(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+
+	t.Run("Status returns parsed report", func(t *testing.T) {
+		t.Parallel()
+		script := "cat <<'EOF'\n" + statusOutput + "EOF"
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, script, 0),
+			logger:     logger,
+		}
+		rep, err := ex.Status(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, rep.Disks, 2)
+		assert.Equal(t, 5, rep.SilentErrors)
+	})
+
+	t.Run("Status returns error", func(t *testing.T) {
+		t.Parallel()
+		ex := &DefaultExecutor{
+			configPath: "dummy.conf",
+			binaryPath: testutils.WriteScriptFile(t, "", 1),
+			logger:     logger,
+		}
+		rep, err := ex.Status(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, rep)
+	})
+}
+
+func TestScrubArgs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Percentage with age", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []string{"--plan", "22", "--older-than", "12"}, scrubArgs("22", 12))
+	})
+
+	for _, plan := range []string{ScrubPlanNew, ScrubPlanBad, ScrubPlanFull} {
+		t.Run("Keyword "+plan+" without age", func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, []string{"--plan", plan}, scrubArgs(plan, 12))
+		})
+	}
 }
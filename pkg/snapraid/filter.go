@@ -0,0 +1,155 @@
+package snapraid
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filter restricts a snapraid command to some data disks or paths.
+// Disks and ExcludeDisks are mutually exclusive.
+type Filter struct {
+	Disks        []string `json:"disks,omitempty"`         // only these data disks are processed ("--filter-disk")
+	ExcludeDisks []string `json:"exclude_disks,omitempty"` // every data disk except these is processed
+	Paths        []string `json:"paths,omitempty"`         // only files matching one of these patterns are processed ("--filter")
+}
+
+// active returns true if the filter restricts anything.
+func (f Filter) active() bool {
+	return len(f.Disks) > 0 || len(f.ExcludeDisks) > 0 || len(f.Paths) > 0
+}
+
+// Filters holds the filter of every command that accepts one. snapraid only honours
+// "--filter" and "--filter-disk" in check and fix, so the diff filter is applied to the
+// parsed diff instead; sync and scrub always process the whole array.
+type Filters struct {
+	Diff  Filter // Diff narrows the reported diff. A run with a diff filter never syncs.
+	Check Filter // Check filters "snapraid check" run by a repair.
+	Fix   Filter // Fix filters "snapraid fix" run by a repair.
+}
+
+// forCommand returns the filter of the named snapraid command.
+func (f Filters) forCommand(cmd string) Filter {
+	switch cmd {
+	case "diff":
+		return f.Diff
+	case "check":
+		return f.Check
+	case "fix":
+		return f.Fix
+	default:
+		return Filter{}
+	}
+}
+
+// active returns the filters that restrict their command, keyed by command name,
+// or nil if there are none.
+func (f Filters) active() map[string]Filter {
+	var res map[string]Filter
+	for _, cmd := range []string{"diff", "check", "fix"} {
+		if filter := f.forCommand(cmd); filter.active() {
+			if res == nil {
+				res = make(map[string]Filter)
+			}
+			res[cmd] = filter
+		}
+	}
+	return res
+}
+
+// filterDiff returns the changes and warnings of d that f selects. A change is selected if
+// the file or, for moves and copies, its source is on a selected disk and matches one of
+// the paths. Like snapraid filter patterns, paths are relative to the disk, "DIR/" selects
+// everything inside a directory and patterns without a slash match in any directory.
+// Entries omitted because of the path limit cannot be matched and are kept.
+func filterDiff(d DiffResult, f Filter) DiffResult {
+	if !f.active() {
+		return d
+	}
+
+	globs := make([]string, len(f.Paths))
+	for i, path := range f.Paths {
+		globs[i] = strings.TrimSuffix(path, "/")
+	}
+	patterns := compileGlobs(globs)
+
+	kept, _ := splitChanges(d, func(c change) bool {
+		disks := []string{c.entry.Disk}
+		if c.transfer != nil && c.transfer.From != "" {
+			disks = append(disks, c.transfer.FromDisk)
+		}
+		for i, path := range c.paths() {
+			if f.selectsDisk(disks[i]) && (len(patterns) == 0 || matchAnyDir(patterns, path)) {
+				return false
+			}
+		}
+		return true
+	})
+	kept.Warnings = slices.DeleteFunc(slices.Clone(d.Warnings), func(w DiffWarning) bool {
+		return w.Disk != "" && !f.selectsDisk(w.Disk)
+	})
+	return kept
+}
+
+// selectsDisk reports whether f selects the data disk. Files on unknown disks are only
+// selected if f names no disks.
+func (f Filter) selectsDisk(disk string) bool {
+	if len(f.Disks) > 0 {
+		return slices.Contains(f.Disks, disk)
+	}
+	return !slices.Contains(f.ExcludeDisks, disk)
+}
+
+// matchAnyDir reports whether any of the patterns matches path or one of its parent directories.
+func matchAnyDir(patterns []*regexp.Regexp, path string) bool {
+	for p := path; p != "." && p != "/" && p != ""; p = filepath.Dir(p) {
+		if matchAny(patterns, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterArgs returns the snapraid arguments of the filter of cmd. Every disk must be declared
+// in the snapraid configuration; excluded disks are turned into a filter of the remaining ones.
+func (d *DefaultExecutor) filterArgs(cmd string) ([]string, error) {
+	f := d.filters.forCommand(cmd)
+	if !f.active() {
+		return nil, nil
+	}
+
+	disks := f.Disks
+	if len(f.Disks) > 0 || len(f.ExcludeDisks) > 0 {
+		declared, err := loadDataDisks(d.configPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range append(slices.Clone(f.Disks), f.ExcludeDisks...) {
+			if _, ok := diskByName(declared, name); !ok {
+				return nil, fmt.Errorf("%s filter: unknown data disk %q", cmd, name)
+			}
+		}
+		if len(f.ExcludeDisks) > 0 {
+			disks = nil
+			for _, disk := range declared {
+				if !slices.Contains(f.ExcludeDisks, disk.Name) {
+					disks = append(disks, disk.Name)
+				}
+			}
+			if len(disks) == 0 {
+				return nil, fmt.Errorf("%s filter: every data disk is excluded", cmd)
+			}
+		}
+	}
+
+	var args []string
+	for _, disk := range disks {
+		args = append(args, "--filter-disk", disk)
+	}
+	for _, path := range f.Paths {
+		args = append(args, "--filter", path)
+	}
+	return args, nil
+}
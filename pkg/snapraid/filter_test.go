@@ -0,0 +1,156 @@
+package snapraid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	t.Parallel()
+
+	t.Run("No active filters", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, Filters{}.active())
+	})
+
+	t.Run("Only active filters are returned", func(t *testing.T) {
+		t.Parallel()
+
+		f := Filters{
+			Diff:  Filter{ExcludeDisks: []string{"d1"}},
+			Check: Filter{Disks: []string{"d2"}},
+			Fix:   Filter{Paths: []string{"movies/*"}},
+		}
+		assert.Equal(t, map[string]Filter{
+			"diff":  {ExcludeDisks: []string{"d1"}},
+			"check": {Disks: []string{"d2"}},
+			"fix":   {Paths: []string{"movies/*"}},
+		}, f.active())
+		assert.Equal(t, Filter{}, f.forCommand("sync"))
+		assert.Equal(t, Filter{}, f.forCommand("scrub"))
+	})
+}
+
+func TestFilterDiff(t *testing.T) {
+	t.Parallel()
+
+	d := parseDiff([]string{
+		"add /mnt/d1/photos/2024/a.jpg",
+		"add /mnt/d1/movies/b.mkv",
+		"remove /mnt/d2/photos/c.jpg",
+		"move /mnt/d2/photos/d.jpg -> /mnt/d1/archive/d.jpg",
+		"add /srv/other/e.txt",
+		"All the files previously present in disk 'd3' at dir '/mnt/d3/'",
+		"     10 equal",
+	})
+	d.resolveDisks([]DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d2", Dir: "/mnt/d2/"}, {Name: "d3", Dir: "/mnt/d3/"}})
+
+	t.Run("No filter keeps everything", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, d, filterDiff(d, Filter{}))
+	})
+
+	t.Run("Included disks", func(t *testing.T) {
+		t.Parallel()
+
+		got := filterDiff(d, Filter{Disks: []string{"d2"}})
+		assert.Empty(t, got.Added)
+		assert.Equal(t, []string{"/mnt/d2/photos/c.jpg"}, got.Removed)
+		assert.Len(t, got.Moves, 1, "Moves are selected by their source")
+		assert.Empty(t, got.Warnings)
+		assert.Equal(t, 10, got.Equal)
+	})
+
+	t.Run("Excluded disks keep unknown disks", func(t *testing.T) {
+		t.Parallel()
+
+		got := filterDiff(d, Filter{ExcludeDisks: []string{"d1", "d2"}})
+		assert.Equal(t, []string{"/srv/other/e.txt"}, got.Added)
+		assert.Empty(t, got.Removed)
+		assert.Empty(t, got.Moves)
+		assert.Len(t, got.Warnings, 1)
+	})
+
+	t.Run("Directory paths select everything inside", func(t *testing.T) {
+		t.Parallel()
+
+		got := filterDiff(d, Filter{Paths: []string{"photos/"}})
+		assert.Equal(t, []string{"/mnt/d1/photos/2024/a.jpg"}, got.Added)
+		assert.Equal(t, []string{"/mnt/d2/photos/c.jpg"}, got.Removed)
+		assert.Len(t, got.Moves, 1)
+		assert.Len(t, got.Entries, 3)
+	})
+
+	t.Run("Anchored paths and disks combine", func(t *testing.T) {
+		t.Parallel()
+
+		got := filterDiff(d, Filter{Disks: []string{"d1"}, Paths: []string{"/movies/*.mkv"}})
+		assert.Equal(t, []string{"/mnt/d1/movies/b.mkv"}, got.Added)
+		assert.Empty(t, got.Removed)
+		assert.Empty(t, got.Moves)
+	})
+}
+
+func TestFilterArgs(t *testing.T) {
+	t.Parallel()
+
+	conf := filepath.Join(t.TempDir(), "snapraid.conf")
+	assert.NoError(t, os.WriteFile(conf, []byte("parity /mnt/parity/snapraid.parity\ndata d1 /mnt/d1/\ndata d2 /mnt/d2/\ndata d3 /mnt/d3/\n"), 0o600))
+
+	t.Run("No filter", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: conf}
+		args, err := d.filterArgs("check")
+		assert.NoError(t, err)
+		assert.Nil(t, args)
+	})
+
+	t.Run("Included disks and paths", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: conf, filters: Filters{Check: Filter{Disks: []string{"d2"}, Paths: []string{"movies/*"}}}}
+		args, err := d.filterArgs("check")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"--filter-disk", "d2", "--filter", "movies/*"}, args)
+	})
+
+	t.Run("Excluded disks select the remaining ones", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: conf, filters: Filters{Fix: Filter{ExcludeDisks: []string{"d2"}}}}
+		args, err := d.filterArgs("fix")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"--filter-disk", "d1", "--filter-disk", "d3"}, args)
+	})
+
+	t.Run("Paths do not need the snapraid config", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: "missing.conf", filters: Filters{Check: Filter{Paths: []string{"a/*"}}}}
+		args, err := d.filterArgs("check")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"--filter", "a/*"}, args)
+	})
+
+	t.Run("Unknown disk", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: conf, filters: Filters{Fix: Filter{ExcludeDisks: []string{"d9"}}}}
+		_, err := d.filterArgs("fix")
+		assert.Error(t, err)
+		assert.EqualError(t, err, `fix filter: unknown data disk "d9"`)
+	})
+
+	t.Run("Every disk excluded", func(t *testing.T) {
+		t.Parallel()
+
+		d := &DefaultExecutor{configPath: conf, filters: Filters{Check: Filter{ExcludeDisks: []string{"d1", "d2", "d3"}}}}
+		_, err := d.filterArgs("check")
+		assert.Error(t, err)
+		assert.EqualError(t, err, "check filter: every data disk is excluded")
+	})
+}
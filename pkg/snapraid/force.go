@@ -0,0 +1,167 @@
+package snapraid
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// zeroSizePattern matches "The file '/mnt/disk1/a.txt' has unexpected zero size!".
+var zeroSizePattern = regexp.MustCompile(`(?i)the file '(.*)' has unexpected zero size`)
+
+// Options that make snapraid sync despite a refusal.
+const (
+	ForceZero  = "--force-zero"  // ForceZero syncs files that were truncated to zero bytes.
+	ForceEmpty = "--force-empty" // ForceEmpty syncs disks that no longer contain any file.
+)
+
+// ForcePolicy allows rerunning a refused sync with ForceZero or ForceEmpty if every affected
+// file or disk is allowlisted. Empty lists never force.
+type ForcePolicy struct {
+	ZeroSize   []string // ZeroSize lists globs of files, relative to their disk, that may be synced with zero size.
+	EmptyDisks []string // EmptyDisks lists data disks that may be synced while missing all their files.
+}
+
+// ZeroSizeFile is a file snapraid refused to sync because it was truncated to zero bytes.
+type ZeroSizeFile struct {
+	Disk    string `json:"disk,omitempty"`    // snapraid data disk name, if known
+	RelPath string `json:"relpath,omitempty"` // path relative to the disk's mount point, if known
+	AbsPath string `json:"abspath"`           // absolute path as printed by snapraid
+}
+
+// SyncRefusal lists why snapraid refused to sync.
+type SyncRefusal struct {
+	ZeroSize   []ZeroSizeFile `json:"zero_size,omitempty"`   // files with unexpected zero size
+	EmptyDisks []DiffWarning  `json:"empty_disks,omitempty"` // disks missing all their files
+}
+
+// refused returns true if the sync output named any zero-size file or empty disk.
+func (s SyncRefusal) refused() bool {
+	return len(s.ZeroSize) > 0 || len(s.EmptyDisks) > 0
+}
+
+// merge adds the files and disks of other.
+func (s *SyncRefusal) merge(other SyncRefusal) {
+	s.ZeroSize = append(s.ZeroSize, other.ZeroSize...)
+	s.EmptyDisks = append(s.EmptyDisks, other.EmptyDisks...)
+}
+
+// parseSyncRefusal collects the zero-size files and empty disks named in the output of a failed sync.
+func parseSyncRefusal(lines []string) SyncRefusal {
+	var s SyncRefusal
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if m := zeroSizePattern.FindStringSubmatch(line); m != nil {
+			s.ZeroSize = append(s.ZeroSize, ZeroSizeFile{AbsPath: m[1]})
+			continue
+		}
+		if w, ok := parseDiffWarning(line); ok && w.Blocking() {
+			s.EmptyDisks = append(s.EmptyDisks, w)
+		}
+	}
+	return s
+}
+
+// resolve attributes every zero-size file to its data disk.
+func (s *SyncRefusal) resolve(disks []DataDisk) {
+	for i, f := range s.ZeroSize {
+		if d, rel, ok := diskByPath(disks, f.AbsPath); ok {
+			s.ZeroSize[i].Disk, s.ZeroSize[i].RelPath = d.Name, rel
+		}
+	}
+}
+
+// options returns the force options that override refusal, or nil unless every zero-size file
+// matches a ZeroSize glob and every empty disk is listed in EmptyDisks.
+func (p ForcePolicy) options(refusal SyncRefusal) []string {
+	var opts []string
+
+	if len(refusal.ZeroSize) > 0 {
+		patterns := compileGlobs(p.ZeroSize)
+		for _, f := range refusal.ZeroSize {
+			// Without a disk the path cannot be matched against relative globs
+			if f.RelPath == "" || !matchAny(patterns, f.RelPath) {
+				return nil
+			}
+		}
+		opts = append(opts, ForceZero)
+	}
+
+	if len(refusal.EmptyDisks) > 0 {
+		for _, w := range refusal.EmptyDisks {
+			if !slices.Contains(p.EmptyDisks, w.Disk) {
+				return nil
+			}
+		}
+		opts = append(opts, ForceEmpty)
+	}
+
+	return opts
+}
+
+// exempt returns d as seen by the threshold gate: the missing or empty disk warnings of
+// disks listed in EmptyDisks and the files removed from those disks are dropped, so the
+// sync reaches snapraid and is forced by options. Warnings of other disks and removed
+// files that could not be attributed to a disk still count.
+func (p ForcePolicy) exempt(d DiffResult) DiffResult {
+	var disks []string
+	d.Warnings = slices.DeleteFunc(slices.Clone(d.Warnings), func(w DiffWarning) bool {
+		if w.Blocking() && slices.Contains(p.EmptyDisks, w.Disk) {
+			disks = append(disks, w.Disk)
+			return true
+		}
+		return false
+	})
+	if len(disks) == 0 {
+		return d
+	}
+
+	gated, _ := splitChanges(d, func(c change) bool {
+		return changeCategories[c.category] == "removed" && slices.Contains(disks, c.entry.Disk)
+	})
+	return gated
+}
+
+// sync runs the sync step. If snapraid refuses to sync zero-size files or empty disks and the
+// force policy allows every affected file and disk, sync is run again with the force options.
+func (r *Runner) sync(ctx context.Context, res *RunResult) error {
+	var force []string
+	for {
+		sync := func(ctx context.Context) (*Summary, error) { return r.exec.Sync(ctx, force) }
+		err := runStep(ctx, r.step(res, "sync", resultStep(sync, &res.Sync)), func(d time.Duration) { res.Timings.Sync += d })
+
+		var refusal *SyncRefusalError
+		if !errors.As(err, &refusal) || ctx.Err() != nil {
+			return err
+		}
+		if res.SyncRefusal == nil {
+			res.SyncRefusal = &SyncRefusal{}
+		}
+		res.SyncRefusal.merge(refusal.SyncRefusal)
+
+		// Every option is added at most once, so a repeated refusal ends the loop
+		opts := slices.DeleteFunc(r.Force.options(refusal.SyncRefusal), func(opt string) bool {
+			return slices.Contains(force, opt)
+		})
+		if len(opts) == 0 {
+			r.log().Warn("Sync refused, affected files or disks are not allowlisted",
+				"kind", refusal.Kind(),
+				"zero_size", len(refusal.ZeroSize),
+				"empty_disks", len(refusal.EmptyDisks),
+				"tag", "sync",
+			)
+			return err
+		}
+
+		force = append(force, opts...)
+		res.ForcedSync = slices.Clone(force)
+		r.log().Warn("Sync refused, rerunning with force options",
+			"kind", refusal.Kind(),
+			"options", force,
+			"tag", "sync",
+		)
+	}
+}
@@ -0,0 +1,174 @@
+package snapraid
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncRefusal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Zero-size files", func(t *testing.T) {
+		t.Parallel()
+
+		s := parseSyncRefusal([]string{
+			"The file '/mnt/d1/app/db.lock' has unexpected zero size!",
+			"If this an expected state you can 'sync' anyway using 'snapraid --force-zero sync'",
+			"The file '/mnt/d2/it's here.txt' has unexpected zero size!",
+		})
+
+		assert.True(t, s.refused())
+		assert.Equal(t, []ZeroSizeFile{
+			{AbsPath: "/mnt/d1/app/db.lock"},
+			{AbsPath: "/mnt/d2/it's here.txt"},
+		}, s.ZeroSize)
+		assert.Empty(t, s.EmptyDisks)
+	})
+
+	t.Run("Empty disk", func(t *testing.T) {
+		t.Parallel()
+
+		s := parseSyncRefusal([]string{
+			"All the files previously present in disk 'd2' at dir '/mnt/d2/'",
+			"are now missing or have been rewritten!",
+			"If you want to 'sync' anyway, use 'snapraid --force-empty sync'.",
+		})
+
+		assert.True(t, s.refused())
+		assert.Empty(t, s.ZeroSize)
+		assert.Len(t, s.EmptyDisks, 1)
+		assert.Equal(t, WarningMissingDisk, s.EmptyDisks[0].Kind)
+		assert.Equal(t, "d2", s.EmptyDisks[0].Disk)
+		assert.Equal(t, "/mnt/d2/", s.EmptyDisks[0].Dir)
+	})
+
+	t.Run("Other failures", func(t *testing.T) {
+		t.Parallel()
+
+		s := parseSyncRefusal([]string{"Error opening file '/mnt/d1/a.txt'", "WARNING! Unexpected time change"})
+		assert.False(t, s.refused())
+	})
+}
+
+func TestSyncRefusalResolve(t *testing.T) {
+	t.Parallel()
+
+	s := SyncRefusal{ZeroSize: []ZeroSizeFile{{AbsPath: "/mnt/d1/app/db.lock"}, {AbsPath: "/srv/other.txt"}}}
+	s.resolve([]DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d2", Dir: "/mnt/d2/"}})
+
+	assert.Equal(t, []ZeroSizeFile{
+		{Disk: "d1", RelPath: "app/db.lock", AbsPath: "/mnt/d1/app/db.lock"},
+		{AbsPath: "/srv/other.txt"},
+	}, s.ZeroSize)
+}
+
+func TestForcePolicyOptions(t *testing.T) {
+	t.Parallel()
+
+	policy := ForcePolicy{ZeroSize: []string{"*.lock", "cache/**"}, EmptyDisks: []string{"d3"}}
+	lock := ZeroSizeFile{Disk: "d1", RelPath: "app/db.lock", AbsPath: "/mnt/d1/app/db.lock"}
+	cached := ZeroSizeFile{Disk: "d1", RelPath: "cache/a/b.bin", AbsPath: "/mnt/d1/cache/a/b.bin"}
+	movie := ZeroSizeFile{Disk: "d1", RelPath: "movies/a.mkv", AbsPath: "/mnt/d1/movies/a.mkv"}
+
+	t.Run("Every zero-size file allowlisted", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []string{ForceZero}, policy.options(SyncRefusal{ZeroSize: []ZeroSizeFile{lock, cached}}))
+	})
+
+	t.Run("One zero-size file not allowlisted", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, policy.options(SyncRefusal{ZeroSize: []ZeroSizeFile{lock, movie}}))
+	})
+
+	t.Run("Zero-size file on an unknown disk", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, policy.options(SyncRefusal{ZeroSize: []ZeroSizeFile{{AbsPath: "/srv/db.lock"}}}))
+	})
+
+	t.Run("Empty disk allowlisted", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []string{ForceEmpty}, policy.options(SyncRefusal{EmptyDisks: []DiffWarning{{Disk: "d3"}}}))
+	})
+
+	t.Run("Both refusals allowlisted", func(t *testing.T) {
+		t.Parallel()
+
+		refusal := SyncRefusal{ZeroSize: []ZeroSizeFile{lock}, EmptyDisks: []DiffWarning{{Disk: "d3"}}}
+		assert.Equal(t, []string{ForceZero, ForceEmpty}, policy.options(refusal))
+	})
+
+	t.Run("Empty disk not allowlisted forces nothing", func(t *testing.T) {
+		t.Parallel()
+
+		refusal := SyncRefusal{ZeroSize: []ZeroSizeFile{lock}, EmptyDisks: []DiffWarning{{Disk: "d1"}}}
+		assert.Nil(t, policy.options(refusal))
+	})
+
+	t.Run("Empty policy forces nothing", func(t *testing.T) {
+		t.Parallel()
+
+		refusal := SyncRefusal{ZeroSize: []ZeroSizeFile{lock}, EmptyDisks: []DiffWarning{{Disk: "d3"}}}
+		assert.Nil(t, ForcePolicy{}.options(refusal))
+	})
+}
+
+func TestForcePolicyExempt(t *testing.T) {
+	t.Parallel()
+
+	d := parseDiff([]string{
+		"remove /mnt/d1/a.txt",
+		"remove /mnt/d3/b.txt",
+		"remove c.txt",
+		"All the files previously present in disk 'd3' at dir '/mnt/d3/'",
+		"All the files previously present in disk 'd2' at dir '/mnt/d2/'",
+	})
+	d.resolveDisks([]DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d2", Dir: "/mnt/d2/"}, {Name: "d3", Dir: "/mnt/d3/"}})
+
+	t.Run("Allowlisted disks are exempt", func(t *testing.T) {
+		t.Parallel()
+
+		gated := ForcePolicy{EmptyDisks: []string{"d3"}}.exempt(d)
+		assert.Equal(t, []string{"/mnt/d1/a.txt", "c.txt"}, gated.Removed, "Unattributed removals still count")
+		assert.Len(t, gated.Entries, 2)
+		assert.Len(t, gated.BlockingWarnings(), 1)
+		assert.Equal(t, "d2", gated.BlockingWarnings()[0].Disk)
+		assert.Len(t, d.Warnings, 2, "The diff result is not modified")
+	})
+
+	t.Run("Empty policy exempts nothing", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, d, ForcePolicy{}.exempt(d))
+	})
+}
+
+func TestSyncRefusalError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Zero-size files", func(t *testing.T) {
+		t.Parallel()
+
+		var files []ZeroSizeFile
+		for i := range 7 {
+			files = append(files, ZeroSizeFile{AbsPath: fmt.Sprintf("/mnt/d1/%d.txt", i)})
+		}
+		cmdErr := &CommandError{Cmd: "sync", Err: errors.New("exit status 1")}
+		err := fmt.Errorf("sync: %w", &SyncRefusalError{SyncRefusal: SyncRefusal{ZeroSize: files}, Err: cmdErr})
+
+		assert.EqualError(t, err, "sync: snapraid refused to sync: 7 files have unexpected zero size "+
+			"(/mnt/d1/0.txt, /mnt/d1/1.txt, /mnt/d1/2.txt, /mnt/d1/3.txt, /mnt/d1/4.txt, and 2 more)")
+		assert.Equal(t, ErrorKindZeroSize, classifyError(err))
+		assert.ErrorIs(t, err, cmdErr)
+	})
+
+	t.Run("Empty disk", func(t *testing.T) {
+		t.Parallel()
+
+		err := &SyncRefusalError{SyncRefusal: SyncRefusal{EmptyDisks: []DiffWarning{{Disk: "d2", Dir: "/mnt/d2/"}}}}
+
+		assert.EqualError(t, err, "snapraid refused to sync: all files on disk d2 at /mnt/d2/ are missing")
+		assert.Equal(t, ErrorKindEmptyDisk, classifyError(err))
+	})
+}
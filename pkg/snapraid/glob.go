@@ -0,0 +1,59 @@
+package snapraid
+
+import (
+	"regexp"
+	"strings"
+)
+
+// compileGlob converts a path glob into a regular expression matching the whole path.
+// "*" matches within a single directory, "**" matches across directories and "?" matches
+// a single character. Everything else is literal, so every pattern compiles.
+func compileGlob(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?") // zero or more directories
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// compileGlobs compiles globs that are relative to the disk root. Globs without a slash
+// match the file name in any directory; a leading slash is ignored.
+func compileGlobs(globs []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(globs))
+	for i, glob := range globs {
+		if !strings.Contains(glob, "/") {
+			glob = "**/" + glob
+		}
+		patterns[i] = compileGlob(strings.TrimPrefix(glob, "/"))
+	}
+	return patterns
+}
+
+// matchAny reports whether any of the patterns matches path. Leading slashes are ignored
+// so patterns are always relative to the disk root.
+func matchAny(patterns []*regexp.Regexp, path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	for _, re := range patterns {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -8,14 +8,15 @@ import (
 	"sync"
 )
 
-// loggerWriter is an io.Writer that splits on newlines and sends each line
-// into a structured slog.Logger with a given tag.
+// loggerWriter is an io.Writer that splits on newlines (and carriage returns, which snapraid
+// uses to redraw its progress line) and sends each line into a structured slog.Logger with a given tag.
 type loggerWriter struct {
-	logger *slog.Logger // logger is the structured slog.Logger instance to send each completed line.
-	tag    string       // tag is the component name to use for each line.
-	level  slog.Level   // level is the slog.Level to use for each line.
-	buf    bytes.Buffer // buf holds partial data until a newline is encountered.
-	mu     sync.Mutex   // mu protects buf if Write is called concurrently.
+	logger   *slog.Logger     // logger is the structured slog.Logger instance to send each completed line.
+	tag      string           // tag is the component name to use for each line.
+	level    slog.Level       // level is the slog.Level to use for each line.
+	progress *progressTracker // progress, if set, consumes progress lines instead of logging them verbatim.
+	buf      bytes.Buffer     // buf holds partial data until a newline is encountered.
+	mu       sync.Mutex       // mu protects buf if Write is called concurrently.
 }
 
 // newLoggerWriter constructs a loggerWriter that tags every line with tag.
@@ -27,8 +28,14 @@ func newLoggerWriter(logger *slog.Logger, tag string, level slog.Level) *loggerW
 	}
 }
 
+// withProgress routes progress lines to pt instead of logging each of them.
+func (lw *loggerWriter) withProgress(pt *progressTracker) *loggerWriter {
+	lw.progress = pt
+	return lw
+}
+
 // Write implements io.Writer. It accumulates bytes in an internal buffer until
-// it sees a newline. For each complete line, it calls logger.Info with that line
+// it sees a newline or carriage return. For each complete line, it calls logger.Info with that line
 // as the message, plus key/value pairs. Any partial (non-terminated) line is
 // kept in buf until the next Write.
 func (lw *loggerWriter) Write(p []byte) (n int, err error) {
@@ -38,8 +45,8 @@ func (lw *loggerWriter) Write(p []byte) (n int, err error) {
 	total := 0
 
 	for len(p) > 0 {
-		// Look for the next newline in p
-		idx := bytes.IndexByte(p, '\n')
+		// Look for the next line terminator in p
+		idx := bytes.IndexAny(p, "\r\n")
 		if idx < 0 {
 			// No newline in this chunk: buffer everything and return
 			m, _ := lw.buf.Write(p)
@@ -47,19 +54,19 @@ func (lw *loggerWriter) Write(p []byte) (n int, err error) {
 			return total, nil
 		}
 
-		// There is at least one newline at p[idx].
-		// Write up through and including that newline into our buffer.
+		// There is at least one line terminator at p[idx].
+		// Write up through and including that terminator into our buffer.
 		m, _ := lw.buf.Write(p[:idx+1])
 		total += m
 
 		// Extract the buffered bytes (a complete line, including '\n').
 		lineBytes := lw.buf.Bytes()
 
-		line := string(bytes.TrimRight(lineBytes, "\n")) // Remove the trailing '\n'
-		line = strings.TrimSpace(line)                   // Trim any surrounding whitespace
+		line := string(bytes.TrimRight(lineBytes, "\r\n")) // Remove the trailing terminator
+		line = strings.TrimSpace(line)                     // Trim any surrounding whitespace
 
-		// If the trimmed line is empty, skip logging
-		if line != "" {
+		// If the trimmed line is empty, skip logging; progress lines are handled by the tracker
+		if line != "" && (lw.progress == nil || !lw.progress.handle(line)) {
 			lw.logger.Log(context.Background(), lw.level, line, "tag", lw.tag)
 		}
 
@@ -0,0 +1,234 @@
+package snapraid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	historyLimit         = 10             // number of past runs the planner learns from
+	defaultScrubInterval = 24 * time.Hour // assumed time between two scrubs without history
+)
+
+// ScrubPlanner computes the scrub plan needed to scrub every block within TargetDays.
+// A zero TargetDays disables the planner and the configured plan is used.
+type ScrubPlanner struct {
+	TargetDays  int           // TargetDays is the longest time in days a block may go without being scrubbed.
+	MaxDuration time.Duration // MaxDuration caps the estimated scrub duration. 0 uses the scrub timeout.
+}
+
+// enabled returns true if the planner replaces the configured plan.
+func (p ScrubPlanner) enabled() bool {
+	return p.TargetDays > 0
+}
+
+// ScrubDecision records the plan chosen by the scrub planner and how it was derived.
+type ScrubDecision struct {
+	Plan       string        `json:"plan,omitempty"`               // percentage passed to "scrub --plan"; empty if the configured plan was used
+	OlderThan  int           `json:"older_than"`                   // days passed to "scrub --older-than"
+	TargetDays int           `json:"target_days"`                  // coverage goal in days
+	Interval   time.Duration `json:"interval"`                     // estimated time until the next scrub
+	DuePercent float64       `json:"due_percent"`                  // estimated share of the array exceeding the goal before the next scrub
+	PerPercent time.Duration `json:"per_percent,omitempty"`        // estimated scrub time per percent of the array, from past runs
+	Estimate   time.Duration `json:"estimated_duration,omitempty"` // estimated duration of the chosen plan
+	Capped     bool          `json:"capped,omitempty"`             // plan was reduced to fit the maximum scrub duration
+	Skip       bool          `json:"skip,omitempty"`               // not even 1% of the array fits the maximum scrub duration, so scrub is skipped
+	Reason     string        `json:"reason"`                       // explanation of the decision
+}
+
+// pastRun is the part of a previous run result the planner learns from.
+type pastRun struct {
+	Timestamp string         `json:"timestamp"`
+	Timings   RunTimings     `json:"timings"`
+	ScrubPlan *ScrubDecision `json:"scrub_plan"`
+}
+
+// planScrub reads the scrub age from `snapraid status` and computes the plan for this run.
+// If status fails or reports no scrub age, the decision keeps the configured plan.
+func (r *Runner) planScrub(ctx context.Context, res *RunResult) ScrubDecision {
+	var st *StatusReport
+	if err := runStep(ctx, r.step(res, "plan", resultStep(r.exec.Status, &st)), func(time.Duration) {}); err != nil {
+		r.log().Warn("Cannot plan scrub, using the configured plan", "error", err, "tag", "scrub")
+		return ScrubDecision{TargetDays: r.Planner.TargetDays, Reason: "status failed, using the configured plan"}
+	}
+
+	history, err := loadHistory(r.outputDir, historyLimit)
+	if err != nil {
+		r.log().Warn("Ignoring past runs for the scrub plan", "error", err, "tag", "scrub")
+	}
+
+	limit := r.Planner.MaxDuration
+	if limit == 0 {
+		limit = r.Timeouts.Scrub
+	}
+
+	d := decideScrub(r.Planner.TargetDays, limit, st, history, r.Timestamp)
+	r.log().Info("Scrub planned",
+		"plan", d.Plan,
+		"older_than", d.OlderThan,
+		"due_percent", d.DuePercent,
+		"estimate", d.Estimate,
+		"reason", d.Reason,
+		"tag", "scrub",
+	)
+	return d
+}
+
+// decideScrub computes the percentage and age of the blocks to scrub so that every block
+// is scrubbed within target days. The share of blocks exceeding the target before the next
+// scrub is estimated from the scrub-age summary; at least the steady rate needed to cover
+// the whole array once per target is scrubbed. If past runs show how long a percent takes,
+// the plan is reduced to fit limit; if not even 1% fits, the scrub is skipped.
+func decideScrub(target int, limit time.Duration, st *StatusReport, history []pastRun, now time.Time) ScrubDecision {
+	d := ScrubDecision{TargetDays: target, Interval: scrubInterval(history, now)}
+	if st == nil || st.ScrubAge == nil {
+		d.Reason = "status reported no scrub age, using the configured plan"
+		return d
+	}
+
+	days := float64(target)
+	interval := d.Interval.Hours() / 24
+	steady := 100 * interval / days
+	d.DuePercent = math.Round(dueShare(*st, days-interval)*10) / 10
+
+	percent := min(math.Ceil(max(steady, d.DuePercent)), 100)
+	d.Reason = fmt.Sprintf("%.1f%% of the array exceeds %dd before the next scrub, steady rate %.1f%%", d.DuePercent, target, steady)
+
+	d.PerPercent = scrubPerPercent(history)
+	if d.PerPercent > 0 {
+		d.Estimate = time.Duration(percent) * d.PerPercent
+		if limit > 0 && d.Estimate > limit {
+			percent = math.Floor(float64(limit) / float64(d.PerPercent))
+			d.Estimate = time.Duration(percent) * d.PerPercent
+			d.Capped = true
+			d.Reason += fmt.Sprintf(", capped to fit %s", limit)
+		}
+		if percent < 1 {
+			d.Skip = true
+			d.Reason += ", less than 1% fits, scrub skipped"
+			return d
+		}
+	}
+
+	// Covering the array at this rate takes cycle days; younger blocks are reached in time
+	if percent > 0 {
+		cycle := 100 / percent * interval
+		d.OlderThan = max(0, int(math.Floor(days-cycle)))
+	}
+	d.Plan = strconv.Itoa(int(percent))
+	return d
+}
+
+// dueShare estimates the share of the array in percent whose last scrub is older than age days.
+// Never scrubbed blocks always count. The scrubbed blocks are assumed to be spread linearly
+// between the newest and the median and between the median and the oldest age.
+func dueShare(st StatusReport, age float64) float64 {
+	a := st.ScrubAge
+	oldest, median, newest := float64(a.Oldest), float64(a.Median), float64(a.Newest)
+
+	var older float64
+	switch {
+	case age >= oldest:
+		older = 0
+	case age >= median:
+		older = 0.5 * (oldest - age) / (oldest - median)
+	case age >= newest:
+		older = 0.5 + 0.5*(median-age)/(median-newest)
+	default:
+		older = 1
+	}
+	return float64(st.NotScrubbed) + float64(100-st.NotScrubbed)*older
+}
+
+// scrubInterval returns the median time between the scrubs of past runs and now,
+// or defaultScrubInterval without history.
+func scrubInterval(history []pastRun, now time.Time) time.Duration {
+	times := []time.Time{now}
+	for _, run := range history {
+		t, err := time.Parse(time.RFC3339, run.Timestamp)
+		if err != nil || run.Timings.Scrub == 0 || !t.Before(now) {
+			continue
+		}
+		times = append(times, t)
+	}
+	if len(times) < 2 {
+		return defaultScrubInterval
+	}
+
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	gaps := make([]time.Duration, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		gaps = append(gaps, times[i].Sub(times[i-1]))
+	}
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2]
+}
+
+// scrubPerPercent returns the average scrub time per percent of the array of past runs
+// whose plan was chosen by the planner, or 0 if there are none.
+func scrubPerPercent(history []pastRun) time.Duration {
+	var total time.Duration
+	n := 0
+	for _, run := range history {
+		if run.ScrubPlan == nil || run.Timings.Scrub == 0 {
+			continue
+		}
+		percent, err := strconv.Atoi(run.ScrubPlan.Plan)
+		if err != nil || percent <= 0 {
+			continue
+		}
+		total += run.Timings.Scrub / time.Duration(percent)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
+
+// loadHistory reads the newest limit run results from dir. Files that cannot be read
+// or decoded are skipped. It returns nil if dir is empty.
+func loadHistory(dir string, limit int) ([]pastRun, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output dir: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, name); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	slices.Reverse(names)
+
+	var runs []pastRun
+	for _, name := range names[:min(limit, len(names))] {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var run pastRun
+		if err := json.Unmarshal(raw, &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,191 @@
+package snapraid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecideScrub(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+	balanced := &StatusReport{ScrubAge: &ScrubAge{Oldest: 20, Median: 10, Newest: 0}}
+	behind := &StatusReport{ScrubAge: &ScrubAge{Oldest: 60, Median: 30, Newest: 0}, NotScrubbed: 10}
+
+	t.Run("No scrub age keeps the configured plan", func(t *testing.T) {
+		t.Parallel()
+
+		d := decideScrub(30, 0, &StatusReport{}, nil, now)
+		assert.Empty(t, d.Plan)
+		assert.Equal(t, 30, d.TargetDays)
+		assert.Contains(t, d.Reason, "configured plan")
+	})
+
+	t.Run("Steady rate when nothing is due", func(t *testing.T) {
+		t.Parallel()
+
+		d := decideScrub(30, 0, balanced, nil, now)
+		assert.Equal(t, "4", d.Plan)
+		assert.Equal(t, 5, d.OlderThan)
+		assert.Equal(t, 24*time.Hour, d.Interval)
+		assert.Equal(t, 0.0, d.DuePercent)
+		assert.False(t, d.Capped)
+		assert.Zero(t, d.Estimate)
+	})
+
+	t.Run("Catches up on overdue blocks", func(t *testing.T) {
+		t.Parallel()
+
+		d := decideScrub(30, 0, behind, nil, now)
+		assert.Equal(t, "57", d.Plan)
+		assert.Equal(t, 28, d.OlderThan)
+		assert.Equal(t, 56.5, d.DuePercent)
+	})
+
+	t.Run("Capped by the maximum duration", func(t *testing.T) {
+		t.Parallel()
+
+		history := []pastRun{{
+			Timestamp: now.Add(-24 * time.Hour).Format(time.RFC3339),
+			Timings:   RunTimings{Scrub: 100 * time.Minute},
+			ScrubPlan: &ScrubDecision{Plan: "10"},
+		}}
+
+		d := decideScrub(30, 30*time.Minute, behind, history, now)
+		assert.Equal(t, "3", d.Plan)
+		assert.Equal(t, 0, d.OlderThan)
+		assert.Equal(t, 10*time.Minute, d.PerPercent)
+		assert.Equal(t, 30*time.Minute, d.Estimate)
+		assert.True(t, d.Capped)
+		assert.Contains(t, d.Reason, "capped to fit 30m0s")
+	})
+
+	t.Run("Skipped if less than one percent fits", func(t *testing.T) {
+		t.Parallel()
+
+		history := []pastRun{{
+			Timestamp: now.Add(-24 * time.Hour).Format(time.RFC3339),
+			Timings:   RunTimings{Scrub: 100 * time.Minute},
+			ScrubPlan: &ScrubDecision{Plan: "10"},
+		}}
+
+		d := decideScrub(30, 5*time.Minute, behind, history, now)
+		assert.True(t, d.Skip)
+		assert.True(t, d.Capped)
+		assert.Empty(t, d.Plan)
+		assert.Contains(t, d.Reason, "less than 1% fits, scrub skipped")
+	})
+}
+
+func TestDueShare(t *testing.T) {
+	t.Parallel()
+
+	st := StatusReport{ScrubAge: &ScrubAge{Oldest: 40, Median: 20, Newest: 0}}
+
+	t.Run("Nothing older than the oldest block", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, 0.0, dueShare(st, 40))
+	})
+
+	t.Run("Half older than the median", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, 50.0, dueShare(st, 20))
+		assert.Equal(t, 25.0, dueShare(st, 30))
+		assert.Equal(t, 75.0, dueShare(st, 10))
+	})
+
+	t.Run("Everything older than the newest block", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, 100.0, dueShare(st, -1))
+	})
+
+	t.Run("Never scrubbed blocks are always due", func(t *testing.T) {
+		t.Parallel()
+		unscrubbed := StatusReport{ScrubAge: &ScrubAge{Oldest: 5, Median: 5, Newest: 5}, NotScrubbed: 20}
+		assert.Equal(t, 20.0, dueShare(unscrubbed, 5))
+	})
+}
+
+func TestScrubInterval(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+	run := func(ago time.Duration, scrub time.Duration) pastRun {
+		return pastRun{Timestamp: now.Add(-ago).Format(time.RFC3339), Timings: RunTimings{Scrub: scrub}}
+	}
+
+	t.Run("Default without history", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, defaultScrubInterval, scrubInterval(nil, now))
+	})
+
+	t.Run("Median gap between scrubs", func(t *testing.T) {
+		t.Parallel()
+
+		history := []pastRun{
+			run(12*time.Hour, time.Minute),
+			run(18*time.Hour, 0), // did not scrub
+			run(24*time.Hour, time.Minute),
+			run(48*time.Hour, time.Minute),
+		}
+		assert.Equal(t, 12*time.Hour, scrubInterval(history, now))
+	})
+}
+
+func TestScrubPerPercent(t *testing.T) {
+	t.Parallel()
+
+	history := []pastRun{
+		{Timings: RunTimings{Scrub: 20 * time.Minute}, ScrubPlan: &ScrubDecision{Plan: "10"}},
+		{Timings: RunTimings{Scrub: 40 * time.Minute}, ScrubPlan: &ScrubDecision{Plan: "10"}},
+		{Timings: RunTimings{Scrub: time.Hour}},                                       // configured plan, unknown percentage
+		{Timings: RunTimings{Scrub: time.Hour}, ScrubPlan: &ScrubDecision{}},          // planner fell back to the configured plan
+		{Timings: RunTimings{}, ScrubPlan: &ScrubDecision{Plan: "5"}},                 // scrub did not run
+		{Timings: RunTimings{Scrub: time.Hour}, ScrubPlan: &ScrubDecision{Plan: "0"}}, // nothing scrubbed
+	}
+
+	assert.Equal(t, 3*time.Minute, scrubPerPercent(history))
+	assert.Zero(t, scrubPerPercent(nil))
+}
+
+func TestLoadHistory(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Newest runs first", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		for _, ts := range []string{"2025-06-01T03:00:00Z", "2025-06-02T03:00:00Z", "2025-06-03T03:00:00Z"} {
+			res := RunResult{Timestamp: ts, Timings: RunTimings{Scrub: time.Minute}}
+			assert.NoError(t, res.WriteJSON(dir))
+		}
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, approvalFile), []byte("{}"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "2025-06-04T03:00:00Z.json"), []byte("{"), 0o600))
+
+		runs, err := loadHistory(dir, 2)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 1, "The newest file is invalid and skipped")
+		assert.Equal(t, "2025-06-03T03:00:00Z", runs[0].Timestamp)
+		assert.Equal(t, time.Minute, runs[0].Timings.Scrub)
+	})
+
+	t.Run("No output dir", func(t *testing.T) {
+		t.Parallel()
+
+		runs, err := loadHistory("", historyLimit)
+		assert.NoError(t, err)
+		assert.Nil(t, runs)
+	})
+
+	t.Run("Missing output dir", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadHistory(filepath.Join(t.TempDir(), "missing"), historyLimit)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read output dir")
+	})
+}
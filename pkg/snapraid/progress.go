@@ -0,0 +1,110 @@
+package snapraid
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// progressPattern matches snapraid progress updates such as
+// "47%, 123456 MB, 152 MB/s, 1024 stripe/s, CPU 12%, 1:23 ETA".
+// Everything after the size is optional because older releases print fewer fields.
+var progressPattern = regexp.MustCompile(
+	`^(\d+)%,\s*(\d+)\s*MB` +
+		`(?:,\s*(\d+)\s*MB/s)?` +
+		`(?:,\s*(\d+)\s*stripe/s)?` +
+		`(?:,\s*CPU\s*(\d+)%)?` +
+		`(?:,\s*(\d+):(\d{2})\s*ETA)?`,
+)
+
+// Progress is a single progress update reported by a running snapraid command.
+type Progress struct {
+	Step       string        `json:"step"`           // step that reported the progress, e.g. "sync"
+	Percent    int           `json:"percent"`        // completion in percent
+	SizeMB     int64         `json:"size_mb"`        // amount of data processed so far in MB
+	Throughput int           `json:"throughput_mbs"` // current throughput in MB/s
+	StripeRate int           `json:"stripe_rate"`    // processed stripes per second
+	CPU        int           `json:"cpu"`            // CPU usage in percent
+	ETA        time.Duration `json:"eta"`            // estimated time until completion
+}
+
+// parseProgress parses a single snapraid progress line for the given step.
+// It returns false if the line is not a progress update.
+func parseProgress(step, line string) (Progress, bool) {
+	m := progressPattern.FindStringSubmatch(line)
+	if m == nil {
+		return Progress{}, false
+	}
+
+	atoi := func(s string) int {
+		n, _ := strconv.Atoi(s) // empty optional groups become 0
+		return n
+	}
+
+	p := Progress{
+		Step:       step,
+		Percent:    atoi(m[1]),
+		Throughput: atoi(m[3]),
+		StripeRate: atoi(m[4]),
+		CPU:        atoi(m[5]),
+		ETA:        time.Duration(atoi(m[6]))*time.Hour + time.Duration(atoi(m[7]))*time.Minute,
+	}
+	p.SizeMB, _ = strconv.ParseInt(m[2], 10, 64)
+	return p, true
+}
+
+// progressTracker turns progress lines into Progress events. Every event is passed to
+// onProgress, but only one line per interval is logged.
+type progressTracker struct {
+	step       string           // step name reported in each event
+	interval   time.Duration    // minimum time between two logged progress lines; 0 logs every update
+	onProgress func(Progress)   // optional callback for every parsed event
+	logger     *slog.Logger     // logger for throttled progress lines
+	mu         sync.Mutex       // protects lastLog
+	lastLog    time.Time        // time the last progress line was logged
+	now        func() time.Time // clock, replaceable in tests
+}
+
+// newProgressTracker constructs a progressTracker for step.
+func newProgressTracker(step string, interval time.Duration, onProgress func(Progress), logger *slog.Logger) *progressTracker {
+	return &progressTracker{
+		step:       step,
+		interval:   interval,
+		onProgress: onProgress,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// handle consumes line if it is a progress update and reports whether it did.
+func (pt *progressTracker) handle(line string) bool {
+	p, ok := parseProgress(pt.step, line)
+	if !ok {
+		return false
+	}
+
+	if pt.onProgress != nil {
+		pt.onProgress(p)
+	}
+
+	pt.mu.Lock()
+	now := pt.now()
+	due := pt.lastLog.IsZero() || now.Sub(pt.lastLog) >= pt.interval
+	if due {
+		pt.lastLog = now
+	}
+	pt.mu.Unlock()
+
+	if due {
+		pt.logger.Log(context.Background(), slog.LevelInfo, line,
+			"tag", pt.step,
+			"percent", p.Percent,
+			"throughput_mbs", p.Throughput,
+			"eta", p.ETA,
+		)
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package snapraid
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Full progress line", func(t *testing.T) {
+		t.Parallel()
+
+		p, ok := parseProgress("sync", "47%, 123456 MB, 152 MB/s, 1024 stripe/s, CPU 12%, 1:23 ETA")
+		assert.True(t, ok)
+		assert.Equal(t, Progress{
+			Step:       "sync",
+			Percent:    47,
+			SizeMB:     123456,
+			Throughput: 152,
+			StripeRate: 1024,
+			CPU:        12,
+			ETA:        time.Hour + 23*time.Minute,
+		}, p)
+	})
+
+	t.Run("Progress line without optional fields", func(t *testing.T) {
+		t.Parallel()
+
+		p, ok := parseProgress("scrub", "3%, 512 MB")
+		assert.True(t, ok)
+		assert.Equal(t, Progress{Step: "scrub", Percent: 3, SizeMB: 512}, p)
+	})
+
+	t.Run("Other lines are not progress", func(t *testing.T) {
+		t.Parallel()
+
+		for _, line := range []string{
+			"Self test...",
+			"100% completed, 1234 MB accessed in 0:10",
+			"Everything OK",
+		} {
+			_, ok := parseProgress("sync", line)
+			assert.False(t, ok, line)
+		}
+	})
+}
+
+func TestProgressTracker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Throttles logging but reports every event", func(t *testing.T) {
+		t.Parallel()
+
+		var collected []entry
+		var events []Progress
+		pt := newProgressTracker("sync", 10*time.Second, func(p Progress) { events = append(events, p) }, newTestLogger(&collected))
+
+		now := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+		pt.now = func() time.Time { return now }
+
+		assert.True(t, pt.handle("1%, 10 MB, 100 MB/s, CPU 5%, 0:30 ETA"))
+		now = now.Add(5 * time.Second)
+		assert.True(t, pt.handle("2%, 20 MB, 100 MB/s, CPU 5%, 0:29 ETA"))
+		now = now.Add(5 * time.Second)
+		assert.True(t, pt.handle("3%, 30 MB, 100 MB/s, CPU 5%, 0:28 ETA"))
+		assert.False(t, pt.handle("Saving state..."))
+
+		assert.Len(t, events, 3)
+		assert.Len(t, collected, 2)
+		assert.Equal(t, int64(1), collected[0].attrs["percent"])
+		assert.Equal(t, int64(3), collected[1].attrs["percent"])
+	})
+}
+
+func TestLoggerWriter_Progress(t *testing.T) {
+	t.Parallel()
+
+	var collected []entry
+	var events []Progress
+	logger := newTestLogger(&collected)
+	lw := newLoggerWriter(logger, "sync", slog.LevelInfo).
+		withProgress(newProgressTracker("sync", time.Hour, func(p Progress) { events = append(events, p) }, logger))
+
+	_, err := lw.Write([]byte("Syncing...\n1%, 10 MB, 50 MB/s, CPU 1%, 0:10 ETA\r2%, 20 MB, 50 MB/s, CPU 1%, 0:09 ETA\r"))
+	assert.NoError(t, err)
+	_, err = lw.Write([]byte("100% completed, 1000 MB accessed in 0:10\n"))
+	assert.NoError(t, err)
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, 2, events[1].Percent)
+
+	msgs := make([]string, 0, len(collected))
+	for _, e := range collected {
+		msgs = append(msgs, e.msg)
+	}
+	assert.Equal(t, []string{
+		"Syncing...",
+		"1%, 10 MB, 50 MB/s, CPU 1%, 0:10 ETA",
+		"100% completed, 1000 MB accessed in 0:10",
+	}, msgs)
+}
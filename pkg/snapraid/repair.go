@@ -0,0 +1,129 @@
+package snapraid
+
+import (
+	"context"
+	"time"
+)
+
+// RepairPolicy selects what happens when scrub or status report blocks with errors.
+type RepairPolicy string
+
+const (
+	RepairOff   RepairPolicy = "off"   // errors are only reported
+	RepairCheck RepairPolicy = "check" // `snapraid check -e` reports whether the bad blocks are recoverable
+	RepairFix   RepairPolicy = "fix"   // `snapraid fix -e` repairs the bad blocks, `snapraid scrub -p bad` confirms the repair
+)
+
+// enabled returns true if the policy runs any snapraid command.
+func (p RepairPolicy) enabled() bool {
+	return p == RepairCheck || p == RepairFix
+}
+
+// RepairReport records an automatic repair.
+type RepairReport struct {
+	Policy        RepairPolicy  `json:"policy"`               // policy the repair ran with
+	Trigger       string        `json:"trigger"`              // step that reported the errors: "scrub" or "status"
+	Errors        int           `json:"errors"`               // number of errors that triggered the repair
+	Phases        []RepairPhase `json:"phases"`               // every executed phase in order
+	Repaired      int           `json:"repaired_blocks"`      // blocks fix reported as recovered
+	Unrecoverable int           `json:"unrecoverable_blocks"` // blocks check or fix reported as unrecoverable
+}
+
+// RepairPhase is a single snapraid command run by a repair.
+type RepairPhase struct {
+	Name     string        `json:"name"`              // "check", "fix" or "rescrub"
+	Summary  *Summary      `json:"summary,omitempty"` // summary reported by the command, if any
+	Duration time.Duration `json:"duration"`          // time the phase took, including retries
+	Error    string        `json:"error,omitempty"`   // error of the phase, if it failed
+}
+
+// Confirmed returns true if the scrub of the repaired blocks succeeded and reported no errors.
+// A rescrub without a summary confirms nothing.
+func (r *RepairReport) Confirmed() bool {
+	if r == nil || len(r.Phases) == 0 {
+		return false
+	}
+	last := r.Phases[len(r.Phases)-1]
+	return last.Name == "rescrub" && last.Error == "" && last.Summary != nil && !last.Summary.HasErrors()
+}
+
+// repairTrigger returns the step that reported errors worth repairing and their number,
+// or an empty step if neither scrub nor status found any.
+func repairTrigger(res RunResult) (string, int) {
+	if s := res.Scrub; s != nil && s.HasErrors() {
+		return "scrub", s.FileErrors + s.IOErrors + s.DataErrors
+	}
+	if s := res.Status; s != nil && s.SilentErrors > 0 {
+		return "status", s.SilentErrors
+	}
+	return "", 0
+}
+
+// repairs reports whether a failed scrub is handed to the repair instead of failing the run:
+// it must have been stopped by the errors it found, not by a cancellation or timeout.
+func (r *Runner) repairs(res *RunResult, err error) bool {
+	return r.Repair.enabled() &&
+		res.Scrub != nil && res.Scrub.HasErrors() &&
+		classifyError(err) == ErrorKindFailed
+}
+
+// repair runs the phases of the repair policy. A failing phase is recorded as the
+// error of res and stops the repair. A phase whose timeout no longer fits into the total
+// budget is skipped together with the phases after it, leaving the repair unconfirmed.
+func (r *Runner) repair(ctx context.Context, res *RunResult, trigger string, errs int) error {
+	res.Repair = &RepairReport{Policy: r.Repair, Trigger: trigger, Errors: errs}
+	r.log().Warn("Errors found, starting repair", "trigger", trigger, "errors", errs, "policy", r.Repair, "tag", "repair")
+
+	type phase struct {
+		name string
+		fn   func(context.Context) (*Summary, error)
+	}
+	phases := []phase{{"check", r.exec.Check}}
+	if r.Repair == RepairFix {
+		rescrub := func(ctx context.Context) (*Summary, error) { return r.exec.Scrub(ctx, ScrubPlanBad, 0) }
+		phases = []phase{{"fix", r.exec.Fix}, {"rescrub", rescrub}}
+	}
+
+	for _, p := range phases {
+		if lacksBudget(ctx, r.Timeouts.Repair) {
+			r.skip(res, p.name)
+			return nil
+		}
+		if err := r.repairPhase(ctx, res, p.name, p.fn); err != nil {
+			return err
+		}
+	}
+	if r.Repair == RepairCheck {
+		return nil
+	}
+
+	r.log().Info("Repair finished",
+		"repaired", res.Repair.Repaired,
+		"unrecoverable", res.Repair.Unrecoverable,
+		"confirmed", res.Repair.Confirmed(),
+		"tag", "repair",
+	)
+	return nil
+}
+
+// repairPhase runs a single repair phase and records it in res.Repair.
+func (r *Runner) repairPhase(ctx context.Context, res *RunResult, name string, fn func(context.Context) (*Summary, error)) error {
+	phase := RepairPhase{Name: name}
+	err := runStep(ctx, r.step(res, name, resultStep(fn, &phase.Summary)), func(d time.Duration) {
+		phase.Duration = d
+		res.Timings.Repair += d
+	})
+	if err != nil {
+		phase.Error = err.Error()
+	}
+	res.Repair.Phases = append(res.Repair.Phases, phase)
+
+	if s := phase.Summary; s != nil && name != "rescrub" {
+		res.Repair.Repaired = s.Recovered
+		res.Repair.Unrecoverable = s.Unrecoverable
+	}
+	if err != nil {
+		res.setError(name, err)
+	}
+	return err
+}
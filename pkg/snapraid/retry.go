@@ -0,0 +1,128 @@
+package snapraid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy controls how often a failed step is retried.
+type RetryPolicy struct {
+	MaxAttempts    int              // MaxAttempts is the total number of attempts including the first. Values <= 1 disable retries.
+	Backoff        time.Duration    // Backoff is the wait before the first retry; it doubles after every further attempt.
+	ExitCodes      []int            // ExitCodes lists exit codes that are considered transient.
+	StderrPatterns []*regexp.Regexp // StderrPatterns match stderr output that is considered transient.
+}
+
+// Retries holds a RetryPolicy per step.
+type Retries struct {
+	Touch  RetryPolicy // Touch is the retry policy for "snapraid touch".
+	Diff   RetryPolicy // Diff is the retry policy for "snapraid diff", including the verification diff after sync.
+	Sync   RetryPolicy // Sync is the retry policy for "snapraid sync".
+	Scrub  RetryPolicy // Scrub is the retry policy for "snapraid scrub".
+	Smart  RetryPolicy // Smart is the retry policy for "snapraid smart".
+	Status RetryPolicy // Status is the retry policy for "snapraid status".
+	Repair RetryPolicy // Repair is the retry policy for each phase of a repair: check, fix and the rescrub.
+}
+
+// forStep returns the policy configured for the named step.
+func (r Retries) forStep(step string) RetryPolicy {
+	switch step {
+	case "touch":
+		return r.Touch
+	case "diff", "verify":
+		return r.Diff
+	case "sync":
+		return r.Sync
+	case "scrub":
+		return r.Scrub
+	case "smart":
+		return r.Smart
+	case "status", "plan":
+		return r.Status
+	case "check", "fix", "rescrub":
+		return r.Repair
+	default:
+		return RetryPolicy{}
+	}
+}
+
+// Attempt records a single execution of a step.
+type Attempt struct {
+	Step     string        `json:"step"`            // step name, e.g. "sync"
+	Attempt  int           `json:"attempt"`         // 1-based attempt number
+	Duration time.Duration `json:"duration"`        // how long this attempt ran
+	Error    string        `json:"error,omitempty"` // error message if the attempt failed
+}
+
+// retryable reports whether err may be resolved by running the step again: its exit code
+// must be listed in ExitCodes or its stderr must match one of StderrPatterns. Without either
+// nothing is retried, so data or I/O errors are not hammered again. Cancellations, timeouts
+// and sync refusals are never retried.
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.As(err, new(*SyncRefusalError)) {
+		return false
+	}
+	if isAcceptableExitCode(err, p.ExitCodes...) {
+		return true
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, re := range p.StderrPatterns {
+		if re.MatchString(cmdErr.Stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+// runWithRetry runs step until it succeeds, the policy is exhausted or the error is not retryable.
+// Every attempt is passed to record.
+func runWithRetry(
+	ctx context.Context,
+	name string,
+	policy RetryPolicy,
+	logger *slog.Logger,
+	step func(context.Context) error,
+	record func(Attempt),
+) error {
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		t0 := time.Now()
+		err := step(ctx)
+
+		a := Attempt{Step: name, Attempt: attempt, Duration: time.Since(t0)}
+		if err != nil {
+			a.Error = err.Error()
+		}
+		record(a)
+
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		logger.Warn("Step failed, retrying",
+			"step", name,
+			"attempt", attempt,
+			"backoff", backoff,
+			"error", err,
+			"tag", "runner",
+		)
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
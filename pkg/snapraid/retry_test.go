@@ -0,0 +1,126 @@
+package snapraid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	t.Parallel()
+
+	exitErr := func(code int) error {
+		return exec.Command("sh", "-c", "exit "+strconv.Itoa(code)).Run()
+	}
+
+	t.Run("Empty policy retries nothing", func(t *testing.T) {
+		t.Parallel()
+		p := RetryPolicy{MaxAttempts: 3}
+		assert.False(t, p.retryable(errors.New("boom")))
+		assert.False(t, p.retryable(&CommandError{Cmd: "scrub", Stderr: "Data error in file", Err: exitErr(1)}))
+	})
+
+	t.Run("Cancellation and timeouts are never retried", func(t *testing.T) {
+		t.Parallel()
+		p := RetryPolicy{}
+		assert.False(t, p.retryable(context.Canceled))
+		assert.False(t, p.retryable(&TimeoutError{Step: "sync", Limit: time.Second}))
+	})
+
+	t.Run("Sync refusals are never retried", func(t *testing.T) {
+		t.Parallel()
+		p := RetryPolicy{ExitCodes: []int{1}}
+		cmdErr := &CommandError{Cmd: "sync", Err: exitErr(1)}
+		assert.False(t, p.retryable(&SyncRefusalError{SyncRefusal: SyncRefusal{EmptyDisks: []DiffWarning{{Disk: "d1"}}}, Err: cmdErr}))
+	})
+
+	t.Run("Matching exit code", func(t *testing.T) {
+		t.Parallel()
+		p := RetryPolicy{ExitCodes: []int{1}}
+		assert.True(t, p.retryable(&CommandError{Cmd: "sync", Err: exitErr(1)}))
+		assert.False(t, p.retryable(&CommandError{Cmd: "sync", Err: exitErr(2)}))
+	})
+
+	t.Run("Matching stderr pattern", func(t *testing.T) {
+		t.Parallel()
+		p := RetryPolicy{StderrPatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)content file .* locked`)}}
+		assert.True(t, p.retryable(&CommandError{Cmd: "sync", Stderr: "Content file '/a' is locked", Err: exitErr(1)}))
+		assert.False(t, p.retryable(&CommandError{Cmd: "sync", Stderr: "disk full", Err: exitErr(1)}))
+		assert.False(t, p.retryable(errors.New("not a command error")))
+	})
+}
+
+func TestRunWithRetry(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.DiscardHandler)
+	locked := &CommandError{Cmd: "sync", Stderr: "Content file '/a' is locked", Err: errors.New("exit status 1")}
+	transient := RetryPolicy{StderrPatterns: []*regexp.Regexp{regexp.MustCompile("locked")}}
+
+	t.Run("Records every attempt", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		step := func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return locked
+			}
+			return nil
+		}
+		policy := transient
+		policy.MaxAttempts, policy.Backoff = 5, time.Millisecond
+
+		var attempts []Attempt
+		err := runWithRetry(context.Background(), "sync", policy, logger, step, func(a Attempt) {
+			attempts = append(attempts, a)
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Len(t, attempts, 3)
+		assert.Equal(t, locked.Error(), attempts[0].Error)
+		assert.Equal(t, 3, attempts[2].Attempt)
+		assert.Empty(t, attempts[2].Error)
+	})
+
+	t.Run("Non-retryable error stops immediately", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		step := func(ctx context.Context) error {
+			calls++
+			return errors.New("permanent")
+		}
+		policy := transient
+		policy.MaxAttempts = 5
+
+		err := runWithRetry(context.Background(), "sync", policy, logger, step, func(Attempt) {})
+
+		assert.EqualError(t, err, "permanent")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Cancellation during backoff", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		step := func(ctx context.Context) error {
+			cancel()
+			return locked
+		}
+		policy := transient
+		policy.MaxAttempts, policy.Backoff = 3, time.Hour
+
+		err := runWithRetry(ctx, "sync", policy, logger, step, func(Attempt) {})
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,213 @@
+package snapraid
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ThresholdRule applies its own limits to diff entries whose path matches Pattern.
+// Rules are evaluated in order and the first matching rule claims an entry, so it is
+// neither counted against later rules nor against the global thresholds.
+type ThresholdRule struct {
+	Pattern   string     // Pattern is a glob relative to the disk root, e.g. "photos/**".
+	Unlimited bool       // Unlimited exempts matching entries from every limit.
+	Limits    Thresholds // Limits for matching entries; –1 disables a category. Limits.Rules is ignored.
+}
+
+// changeCategories names the DiffResult lists in the order returned by entries.
+var changeCategories = []string{"added", "removed", "updated", "moved", "copied", "restored"}
+
+// changeCounts holds the number of entries per change category, indexed like changeCategories.
+type changeCounts [6]int
+
+// entries returns the change lists in the order of changeCategories.
+func (d DiffResult) entries() [][]string {
+	return [][]string{d.Added, d.Removed, d.Updated, d.Moved, d.Copied, d.Restored}
+}
+
+// counts returns the number of changed files per category, including omitted entries.
+func (d DiffResult) counts() changeCounts {
+	var c changeCounts
+	for i, list := range d.entries() {
+		c[i] = len(list) + d.Omitted[changeCategories[i]]
+	}
+	return c
+}
+
+// list returns a pointer to the change list of category.
+func (d *DiffResult) list(category string) *[]string {
+	switch category {
+	case "added":
+		return &d.Added
+	case "removed":
+		return &d.Removed
+	case "updated":
+		return &d.Updated
+	case "moved":
+		return &d.Moved
+	case "copied":
+		return &d.Copied
+	default:
+		return &d.Restored
+	}
+}
+
+// categoryLimit is the absolute and relative limit of one change category.
+type categoryLimit struct {
+	count   int     // absolute limit; –1 disables
+	percent float64 // relative limit in percent of the array; 0 disables
+}
+
+// limits returns the limits in the order of changeCategories.
+func (t Thresholds) limits() [6]categoryLimit {
+	return [6]categoryLimit{
+		{t.Add, t.AddPercent},
+		{t.Remove, t.RemovePercent},
+		{t.Update, t.UpdatePercent},
+		{t.Move, t.MovePercent},
+		{t.Copy, t.CopyPercent},
+		{t.Restore, t.RestorePercent},
+	}
+}
+
+// change is one changed file of a DiffResult: the item of its legacy list together with its
+// entry and, for moves and copies, its transfer.
+type change struct {
+	category int       // index into changeCategories
+	line     string    // item of the legacy list, "old -> new" for moves and copies
+	entry    DiffEntry // entry describing the file, or the new location of a move or copy
+	transfer *Transfer // source and destination of a move or copy, nil otherwise
+}
+
+// changes pairs every item of the legacy lists with its entry and transfer, in list order.
+// Results without entries, e.g. older result files, get entries derived from the lists.
+func (d DiffResult) changes() []change {
+	byCategory := make(map[string][]DiffEntry, len(changeCategories))
+	for _, e := range d.Entries {
+		byCategory[e.Change] = append(byCategory[e.Change], e)
+	}
+
+	var changes []change
+	for i, list := range d.entries() {
+		category := changeCategories[i]
+		entries := byCategory[category]
+		for k, line := range list {
+			c := change{category: i, line: line}
+			switch category {
+			case "moved":
+				c.transfer = transferAt(d.Moves, k, line)
+			case "copied":
+				c.transfer = transferAt(d.Copies, k, line)
+			}
+
+			switch {
+			case k < len(entries):
+				c.entry = entries[k]
+			case c.transfer != nil:
+				c.entry = newEntry(category, c.transfer.ToDisk, c.transfer.To)
+			default:
+				c.entry = newEntry(category, "", line)
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// transferAt returns the k-th transfer, or one parsed from its legacy list item line.
+func transferAt(transfers []Transfer, k int, line string) *Transfer {
+	if k < len(transfers) {
+		t := transfers[k]
+		return &t
+	}
+	from, to, ok := strings.Cut(line, " -> ")
+	if !ok {
+		return &Transfer{To: line}
+	}
+	return &Transfer{From: from, To: to}
+}
+
+// paths returns the paths of c to match against globs: the path relative to its disk and,
+// for moves and copies, the source relative to its disk. Paths that could not be attributed
+// to a disk are matched as printed by snapraid.
+func (c change) paths() []string {
+	paths := []string{cmp.Or(c.entry.RelPath, c.entry.AbsPath)}
+	if c.transfer != nil && c.transfer.From != "" {
+		paths = append(paths, cmp.Or(c.transfer.FromRelPath, c.transfer.From))
+	}
+	return paths
+}
+
+// countByRule counts the changes of result per rule. The last element of the returned
+// slice holds the changes no rule matched, which are checked against the global thresholds.
+// Entries omitted because of the path limit cannot be matched and count as unmatched.
+func countByRule(result DiffResult, rules []ThresholdRule) []changeCounts {
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		patterns[i] = compileGlob(rule.Pattern)
+	}
+
+	counts := make([]changeCounts, len(rules)+1)
+	for _, c := range result.changes() {
+		counts[firstMatch(patterns, c.paths())][c.category]++
+	}
+	for i, category := range changeCategories {
+		counts[len(rules)][i] += result.Omitted[category]
+	}
+	return counts
+}
+
+// firstMatch returns the index of the first pattern matching any of paths, or len(patterns).
+func firstMatch(patterns []*regexp.Regexp, paths []string) int {
+	for i, re := range patterns {
+		for _, p := range paths {
+			if re.MatchString(strings.TrimPrefix(p, "/")) {
+				return i
+			}
+		}
+	}
+	return len(patterns)
+}
+
+// splitIgnored moves the changes of d matching any of the ignore globs into a separate result.
+// Moves and copies are ignored if either path matches.
+func splitIgnored(d DiffResult, globs []string) (kept, ignored DiffResult) {
+	if len(globs) == 0 {
+		return d, ignored
+	}
+
+	patterns := compileGlobs(globs)
+	return splitChanges(d, func(c change) bool {
+		return slices.ContainsFunc(c.paths(), func(p string) bool { return matchAny(patterns, p) })
+	})
+}
+
+// splitChanges moves the changes of d for which match returns true into a separate result.
+// The legacy lists, entries and transfers of a change always end up in the same result.
+func splitChanges(d DiffResult, match func(change) bool) (kept, split DiffResult) {
+	kept = d
+	kept.Added, kept.Removed, kept.Updated = nil, nil, nil
+	kept.Moved, kept.Copied, kept.Restored = nil, nil, nil
+	kept.Entries, kept.Moves, kept.Copies = nil, nil, nil
+
+	for _, c := range d.changes() {
+		dst := &kept
+		if match(c) {
+			dst = &split
+		}
+
+		category := changeCategories[c.category]
+		list := dst.list(category)
+		*list = append(*list, c.line)
+		dst.Entries = append(dst.Entries, c.entry)
+		switch category {
+		case "moved":
+			dst.Moves = append(dst.Moves, *c.transfer)
+		case "copied":
+			dst.Copies = append(dst.Copies, *c.transfer)
+		}
+	}
+	return kept, split
+}
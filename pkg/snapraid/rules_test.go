@@ -0,0 +1,197 @@
+package snapraid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"photos/**", "photos/2024/a.jpg", true},
+		{"photos/**", "photos/a.jpg", true},
+		{"photos/**", "videos/a.mp4", false},
+		{"photos/*", "photos/2024/a.jpg", false},
+		{"**/*.tmp", "a.tmp", true},
+		{"**/*.tmp", "downloads/x/a.tmp", true},
+		{"docs/?.txt", "docs/a.txt", true},
+		{"docs/?.txt", "docs/ab.txt", false},
+		{"movies/Film (2000)/*", "movies/Film (2000)/a.mkv", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.match, compileGlob(tc.pattern).MatchString(tc.path))
+		})
+	}
+}
+
+func TestValidateThresholdRules(t *testing.T) {
+	t.Parallel()
+
+	disabled := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+
+	t.Run("Rule fires and is reported", func(t *testing.T) {
+		t.Parallel()
+
+		photos := disabled
+		photos.Remove = 0
+		photos.Update = 5
+
+		th := disabled
+		th.Rules = []ThresholdRule{{Pattern: "photos/**", Limits: photos}}
+
+		result := DiffResult{Removed: []string{"photos/a.jpg", "downloads/b.iso"}}
+
+		err := validateThresholds(result, th)
+		assert.EqualError(t, err, "removed files in photos/** exceed threshold (1 > 0)")
+
+		var violations ThresholdViolations
+		assert.ErrorAs(t, err, &violations)
+		assert.Equal(t, "photos/**", violations[0].Rule)
+	})
+
+	t.Run("Unlimited rule exempts entries from the global thresholds", func(t *testing.T) {
+		t.Parallel()
+
+		th := disabled
+		th.Remove = 1
+		th.Rules = []ThresholdRule{{Pattern: "downloads/**", Unlimited: true}}
+
+		result := DiffResult{Removed: []string{"downloads/a.iso", "downloads/b.iso", "docs/c.txt"}}
+
+		assert.NoError(t, validateThresholds(result, th))
+
+		result.Removed = append(result.Removed, "docs/d.txt")
+		assert.EqualError(t, validateThresholds(result, th), "removed files exceed threshold (2 > 1)")
+	})
+
+	t.Run("First matching rule wins", func(t *testing.T) {
+		t.Parallel()
+
+		strict := disabled
+		strict.Add = 0
+
+		th := disabled
+		th.Rules = []ThresholdRule{
+			{Pattern: "photos/inbox/**", Unlimited: true},
+			{Pattern: "photos/**", Limits: strict},
+		}
+
+		assert.NoError(t, validateThresholds(DiffResult{Added: []string{"photos/inbox/new.jpg"}}, th))
+		assert.Error(t, validateThresholds(DiffResult{Added: []string{"photos/2024/new.jpg"}}, th))
+	})
+
+	t.Run("Moves match on either path", func(t *testing.T) {
+		t.Parallel()
+
+		strict := disabled
+		strict.Move = 0
+
+		th := disabled
+		th.Rules = []ThresholdRule{{Pattern: "photos/**", Limits: strict}}
+
+		err := validateThresholds(DiffResult{Moved: []string{"photos/a.jpg -> trash/a.jpg"}}, th)
+		assert.EqualError(t, err, "moved files in photos/** exceed threshold (1 > 0)")
+	})
+
+	t.Run("Absolute text output matches by disk-relative path", func(t *testing.T) {
+		t.Parallel()
+
+		strict := disabled
+		strict.Remove = 0
+
+		th := disabled
+		th.Rules = []ThresholdRule{{Pattern: "photos/**", Limits: strict}}
+
+		result := parseDiff([]string{
+			"remove /mnt/disk1/photos/b.jpg",
+			"move /mnt/disk2/photos/c.jpg -> /mnt/disk2/trash/c.jpg",
+		})
+		result.resolveDisks([]DataDisk{{Name: "d1", Dir: "/mnt/disk1/"}, {Name: "d2", Dir: "/mnt/disk2/"}})
+
+		counts := countByRule(result, th.Rules)
+		assert.Equal(t, changeCounts{0, 1, 0, 1, 0, 0}, counts[0])
+		assert.Equal(t, changeCounts{}, counts[1])
+		assert.EqualError(t, validateThresholds(result, th), "removed files in photos/** exceed threshold (1 > 0)")
+	})
+}
+
+func TestSplitIgnored(t *testing.T) {
+	t.Parallel()
+
+	diff := parseDiff([]string{
+		"add /mnt/disk1/movies/a.mkv",
+		"add /mnt/disk1/movies/a.nfo",
+		"add /mnt/disk2/.DS_Store",
+		"remove /mnt/disk2/photos/Thumbs.db",
+		"remove /mnt/disk2/photos/b.jpg",
+		"move /mnt/disk1/movies/old.nfo -> /mnt/disk1/movies/new.nfo",
+		"move /mnt/disk1/movies/c.mkv -> /mnt/disk1/archive/c.mkv",
+		"     100 equal",
+	})
+	diff.resolveDisks([]DataDisk{{Name: "d1", Dir: "/mnt/disk1/"}, {Name: "d2", Dir: "/mnt/disk2/"}})
+
+	relPaths := func(entries []DiffEntry) []string {
+		var paths []string
+		for _, e := range entries {
+			paths = append(paths, e.RelPath)
+		}
+		return paths
+	}
+
+	t.Run("No globs keeps everything", func(t *testing.T) {
+		t.Parallel()
+
+		kept, ignored := splitIgnored(diff, nil)
+		assert.Equal(t, diff, kept)
+		assert.False(t, ignored.HasChanges())
+	})
+
+	t.Run("Globs without slash match any directory", func(t *testing.T) {
+		t.Parallel()
+
+		kept, ignored := splitIgnored(diff, []string{".DS_Store", "Thumbs.db", "*.nfo"})
+
+		assert.Equal(t, 100, kept.Equal)
+		assert.Equal(t, []string{"/mnt/disk1/movies/a.mkv"}, kept.Added)
+		assert.Equal(t, []string{"/mnt/disk2/photos/b.jpg"}, kept.Removed)
+		assert.Equal(t, []string{"/mnt/disk1/movies/c.mkv -> /mnt/disk1/archive/c.mkv"}, kept.Moved)
+		assert.Equal(t, []string{"movies/a.mkv", "photos/b.jpg", "archive/c.mkv"}, relPaths(kept.Entries))
+		assert.Len(t, kept.Moves, 1)
+		assert.Equal(t, "movies/c.mkv", kept.Moves[0].FromRelPath)
+
+		assert.Equal(t, []string{"/mnt/disk1/movies/a.nfo", "/mnt/disk2/.DS_Store"}, ignored.Added)
+		assert.Equal(t, []string{"/mnt/disk2/photos/Thumbs.db"}, ignored.Removed)
+		assert.Equal(t, []string{"/mnt/disk1/movies/old.nfo -> /mnt/disk1/movies/new.nfo"}, ignored.Moved)
+		assert.Equal(t, []string{"movies/a.nfo", ".DS_Store", "photos/Thumbs.db", "movies/new.nfo"}, relPaths(ignored.Entries))
+		assert.Len(t, ignored.Moves, 1)
+		assert.Equal(t, "movies/old.nfo", ignored.Moves[0].FromRelPath)
+		assert.Equal(t, 4, ignored.Changes())
+	})
+
+	t.Run("Globs with slash are anchored at the disk root", func(t *testing.T) {
+		t.Parallel()
+
+		kept, ignored := splitIgnored(diff, []string{"photos/*.db"})
+		assert.Equal(t, []string{"/mnt/disk2/photos/b.jpg"}, kept.Removed)
+		assert.Equal(t, []string{"/mnt/disk2/photos/Thumbs.db"}, ignored.Removed)
+		assert.Equal(t, []DiffEntry{{Change: "removed", Disk: "d2", RelPath: "photos/Thumbs.db", AbsPath: "/mnt/disk2/photos/Thumbs.db"}}, ignored.Entries)
+		assert.Nil(t, ignored.Added)
+	})
+
+	t.Run("Moves are ignored by their source", func(t *testing.T) {
+		t.Parallel()
+
+		kept, ignored := splitIgnored(diff, []string{"movies/c.mkv"})
+		assert.Equal(t, []string{"/mnt/disk1/movies/c.mkv -> /mnt/disk1/archive/c.mkv"}, ignored.Moved)
+		assert.Equal(t, []string{"archive/c.mkv"}, relPaths(ignored.Entries))
+		assert.Equal(t, 6, kept.Changes())
+	})
+}
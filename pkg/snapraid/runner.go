@@ -1,18 +1,24 @@
 package snapraid
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"os"
 	"time"
 )
 
 // Steps defines which SnapRAID subcommands to run.
 type Steps struct {
-	Touch bool // Touch enables the "snapraid touch" step.
-	Scrub bool // Scrub enables the "snapraid scrub" step.
-	Smart bool // Smart enables the "snapraid smart" step.
+	Touch  bool // Touch enables the "snapraid touch" step.
+	Scrub  bool // Scrub enables the "snapraid scrub" step.
+	Smart  bool // Smart enables the "snapraid smart" step.
+	Status bool // Status enables the "snapraid status" step.
+	Verify bool // Verify enables a "snapraid diff" after sync that expects no changes.
 }
 
-// Thresholds defines numeric limits on detected file changes before blocking sync.
+// Thresholds defines limits on detected file changes before blocking sync.
+// Absolute and relative limits can be combined; exceeding either one blocks sync.
 type Thresholds struct {
 	Add     int // Add is the maximum number of added files allowed. –1 disables.
 	Remove  int // Remove is the maximum number of removed files allowed. –1 disables.
@@ -20,74 +26,242 @@ type Thresholds struct {
 	Move    int // Move is the maximum number of moved files allowed. –1 disables.
 	Copy    int // Copy is the maximum number of copied files allowed. –1 disables.
 	Restore int // Restore is the maximum number of restored files allowed. –1 disables.
+
+	AddPercent     float64 // AddPercent is the maximum share of added files in percent of the array. 0 disables.
+	RemovePercent  float64 // RemovePercent is the maximum share of removed files in percent of the array. 0 disables.
+	UpdatePercent  float64 // UpdatePercent is the maximum share of updated files in percent of the array. 0 disables.
+	MovePercent    float64 // MovePercent is the maximum share of moved files in percent of the array. 0 disables.
+	CopyPercent    float64 // CopyPercent is the maximum share of copied files in percent of the array. 0 disables.
+	RestorePercent float64 // RestorePercent is the maximum share of restored files in percent of the array. 0 disables.
+
+	Rules  []ThresholdRule // Rules apply their own limits to matching paths, evaluated in order before the limits above.
+	Ignore []string        // Ignore lists globs of paths that are not counted against any limit. They are still synced.
+}
+
+// Timeouts limits how long each step and the whole run may take. Zero disables a limit.
+type Timeouts struct {
+	Touch  time.Duration // Touch limits the "snapraid touch" step.
+	Diff   time.Duration // Diff limits the "snapraid diff" step and the verification diff after sync.
+	Sync   time.Duration // Sync limits the "snapraid sync" step.
+	Scrub  time.Duration // Scrub limits the "snapraid scrub" step.
+	Smart  time.Duration // Smart limits the "snapraid smart" step.
+	Status time.Duration // Status limits the "snapraid status" step.
+	Repair time.Duration // Repair limits each phase of a repair: check, fix and the rescrub.
+	Total  time.Duration // Total is the budget for the whole run. Optional steps are skipped if their limit no longer fits.
+}
+
+// forStep returns the timeout configured for the named step.
+func (t Timeouts) forStep(step string) time.Duration {
+	switch step {
+	case "touch":
+		return t.Touch
+	case "diff", "verify":
+		return t.Diff
+	case "sync":
+		return t.Sync
+	case "scrub":
+		return t.Scrub
+	case "smart":
+		return t.Smart
+	case "status", "plan":
+		return t.Status
+	case "check", "fix", "rescrub":
+		return t.Repair
+	default:
+		return 0
+	}
+}
+
+// DiffOptions bounds the memory used for the parsed diff.
+type DiffOptions struct {
+	MaxPaths int    // MaxPaths is the maximum number of paths kept per change category. 0 keeps all.
+	SpillDir string // SpillDir, if set, receives a file listing every changed path.
+}
+
+// CommandOptions control how the snapraid binary is invoked.
+type CommandOptions struct {
+	Quiet     bool                // Quiet passes "--quiet" to every command except sync, scrub, check and fix, whose progress it would suppress.
+	ExtraArgs map[string][]string // ExtraArgs are passed to the snapraid command of the same name, e.g. "sync".
+}
+
+// Settle configures the quiescence check before sync: diff is repeated after Interval until two
+// consecutive results are identical. A zero Interval disables the check.
+type Settle struct {
+	Interval time.Duration // Interval is the wait between two diffs.
+	Attempts int           // Attempts is how many repeated diffs are compared before sync is deferred.
 }
 
 // RunResult holds the summary of a completed run.
 type RunResult struct {
-	Timestamp string     `json:"timestamp"`       // RFC3339 timestamp when run started
-	Result    DiffResult `json:"result"`          // parsed diff summary + file lists
-	Timings   RunTimings `json:"timings"`         // per-step durations + total
-	Error     error      `json:"error,omitempty"` // any error that occurred
+	Timestamp         string              `json:"timestamp"`                      // RFC3339 timestamp when run started
+	Result            DiffResult          `json:"result"`                         // parsed diff summary + file lists
+	Ignored           *DiffResult         `json:"ignored,omitempty"`              // diff entries matching Thresholds.Ignore, excluded from Result
+	Sync              *Summary            `json:"sync,omitempty"`                 // summary reported by sync, if it ran
+	Scrub             *Summary            `json:"scrub,omitempty"`                // summary reported by scrub, if it ran
+	Smart             *SmartReport        `json:"smart,omitempty"`                // per-disk health report, if smart ran
+	Status            *StatusReport       `json:"status,omitempty"`               // parsed array status, if status ran
+	Timings           RunTimings          `json:"timings"`                        // per-step durations + total
+	Error             error               `json:"error,omitempty"`                // any error that occurred
+	ErrorKind         ErrorKind           `json:"error_kind,omitempty"`           // classification of Error (e.g. "cancelled")
+	FailedStep        string              `json:"failed_step,omitempty"`          // step that was running when Error occurred
+	Skipped           []string            `json:"skipped_steps,omitempty"`        // optional steps skipped because the time budget was nearly used up
+	Attempts          []Attempt           `json:"attempts,omitempty"`             // every executed attempt of every step, including retries
+	Violations        ThresholdViolations `json:"threshold_violations,omitempty"` // every breach of the threshold gate
+	Mismatches        ParseIntegrityError `json:"count_mismatches,omitempty"`     // diff categories whose parsed entries differ from snapraid's counters
+	Fingerprint       string              `json:"fingerprint,omitempty"`          // fingerprint of the diff, set if the threshold gate blocked sync
+	ApprovedRun       string              `json:"approved_run,omitempty"`         // timestamp of the approved run that released a blocked sync
+	SettleChecks      int                 `json:"settle_checks,omitempty"`        // number of repeated diffs compared by the quiescence check
+	Deferred          bool                `json:"deferred,omitempty"`             // sync was postponed because the changes did not settle
+	ChangedDuringSync *DiffResult         `json:"changed_during_sync,omitempty"`  // files the verification diff after sync still reported, not fully protected
+	Repair            *RepairReport       `json:"repair,omitempty"`               // automatic repair of blocks with errors, if one ran
+	ScrubPlan         *ScrubDecision      `json:"scrub_plan,omitempty"`           // plan chosen by the scrub planner, if it ran
+	Filters           map[string]Filter   `json:"filters,omitempty"`              // active disk and path filters, keyed by snapraid command
+	SyncRefusal       *SyncRefusal        `json:"sync_refusal,omitempty"`         // zero-size files and empty disks sync refused, if any
+	ForcedSync        []string            `json:"forced_sync,omitempty"`          // force options sync was rerun with after a refusal
+}
+
+// HasChanges returns true if any files were added/removed/updated/moved/copied/restored,
+// including changes ignored by the threshold gate.
+func (r RunResult) HasChanges() bool {
+	return r.Result.HasChanges() || (r.Ignored != nil && r.Ignored.HasChanges())
 }
 
-// HasChanges returns true if any files were added/removed/updated/moved/copied/restored.
-func (r RunResult) HasChanges() bool { return r.Result.HasChanges() }
+// Cancelled returns true if the run was interrupted before it completed.
+func (r RunResult) Cancelled() bool { return r.ErrorKind == ErrorKindCancelled }
+
+// TimedOut returns true if a step or the total run budget exceeded its time limit.
+func (r RunResult) TimedOut() bool { return r.ErrorKind == ErrorKindTimeout }
+
+// AttemptsFor returns how often the named step was attempted.
+func (r RunResult) AttemptsFor(step string) int {
+	n := 0
+	for _, a := range r.Attempts {
+		if a.Step == step {
+			n++
+		}
+	}
+	return n
+}
+
+// setError records err together with the step it occurred in and its kind.
+func (r *RunResult) setError(step string, err error) {
+	r.Error = err
+	r.FailedStep = step
+	r.ErrorKind = classifyError(err)
+}
 
 // RunTimings captures the duration of each subcommand and the total.
 type RunTimings struct {
-	Touch time.Duration `json:"touch"`
-	Diff  time.Duration `json:"diff"`
-	Sync  time.Duration `json:"sync"`
-	Scrub time.Duration `json:"scrub"`
-	Smart time.Duration `json:"smart"`
-	Total time.Duration `json:"total"`
+	Touch  time.Duration `json:"touch"`
+	Diff   time.Duration `json:"diff"`
+	Sync   time.Duration `json:"sync"`
+	Verify time.Duration `json:"verify"`
+	Scrub  time.Duration `json:"scrub"`
+	Smart  time.Duration `json:"smart"`
+	Status time.Duration `json:"status"`
+	Repair time.Duration `json:"repair"`
+	Total  time.Duration `json:"total"`
 }
 
 // Runner coordinates a full SnapRAID workflow based on its configuration.
 type Runner struct {
-	Steps      Steps      // which subcommands to run: Touch, Scrub, Smart
-	Thresholds Thresholds // numeric limits per change type
-	DryRun     bool       // if true, skip sync/scrub/smart
+	Steps      Steps        // which subcommands to run: Touch, Scrub, Smart, Status
+	Thresholds Thresholds   // numeric limits per change type
+	Tolerance  int          // how many diff entries may differ from an approved run
+	Settle     Settle       // quiescence check before sync
+	Repair     RepairPolicy // what to do about blocks with errors found by scrub or status
+	Planner    ScrubPlanner // computes the scrub plan from the scrub age, if enabled
+	Filters    Filters      // disk and path filters of diff, check and fix
+	Force      ForcePolicy  // zero-size files and empty disks a refused sync may be forced for
+	Timeouts   Timeouts     // per-step time limits and total run budget
+	Retries    Retries      // per-step retry policies
+	DryRun     bool         // if true, skip sync/scrub/smart/status
 
-	Logger    *slog.Logger // structured logger for real‐time output
-	Timestamp time.Time    // UTC time when Runner was created
+	Logger     *slog.Logger   // structured logger for real‐time output
+	Timestamp  time.Time      // UTC time when Runner was created
+	OnProgress func(Progress) // optional callback for progress updates of sync/scrub; called from the output-reading goroutine
 
-	exec Snapraid // performs Touch, Diff, Sync, Scrub, Smart
+	outputDir string   // directory holding run results and the pending approval
+	exec      Snapraid // performs Touch, Diff, Sync, Scrub, Smart, Status
 }
 
-// NewRunner constructs a Runner with the given parameters. It installs a DefaultExecutor by default.
-func NewRunner(
-	configPath, binaryPath, outputPath string,
-	steps Steps,
-	thresholds Thresholds,
-	scrubPlan, scrubOlder int,
-	dryRun bool,
-	logger *slog.Logger,
-) *Runner {
+// RunnerOptions configures a Runner and its DefaultExecutor, see NewRunner.
+type RunnerOptions struct {
+	ConfigPath string // path of the snapraid configuration
+	BinaryPath string // path of the snapraid binary
+	OutputDir  string // directory holding run results and the pending approval; empty disables both
+
+	Steps      Steps          // which subcommands to run: Touch, Scrub, Smart, Status
+	Thresholds Thresholds     // numeric limits per change type
+	Tolerance  int            // how many diff entries may differ from an approved run
+	Timeouts   Timeouts       // per-step time limits and total run budget
+	Retries    Retries        // per-step retry policies
+	Diff       DiffOptions    // memory bounds of the parsed diff
+	Settle     Settle         // quiescence check before sync
+	Repair     RepairPolicy   // what to do about blocks with errors found by scrub or status
+	Planner    ScrubPlanner   // computes the scrub plan from the scrub age, if enabled
+	Filters    Filters        // disk and path filters of diff, check and fix
+	Command    CommandOptions // how the snapraid binary is invoked
+	Force      ForcePolicy    // zero-size files and empty disks a refused sync may be forced for
+
+	ScrubPlan        string        // configured scrub plan, used unless the planner chooses one
+	ScrubOlderThan   int           // configured scrub age in days, used unless the planner chooses one
+	GracePeriod      time.Duration // time snapraid gets to exit after an interrupt before it is killed
+	ProgressInterval time.Duration // minimum time between logged progress updates
+	DryRun           bool          // if true, skip sync/scrub/smart/status
+}
+
+// NewRunner constructs a Runner from opts. It installs a DefaultExecutor by default.
+func NewRunner(opts RunnerOptions, logger *slog.Logger) *Runner {
 	r := &Runner{
-		Steps:      steps,
-		Thresholds: thresholds,
-		DryRun:     dryRun,
+		Steps:      opts.Steps,
+		Thresholds: opts.Thresholds,
+		Tolerance:  opts.Tolerance,
+		Settle:     opts.Settle,
+		Repair:     opts.Repair,
+		Planner:    opts.Planner,
+		Filters:    opts.Filters,
+		Force:      opts.Force,
+		Timeouts:   opts.Timeouts,
+		Retries:    opts.Retries,
+		DryRun:     opts.DryRun,
 		Logger:     logger,
+		outputDir:  opts.OutputDir,
 	}
 	r.exec = &DefaultExecutor{
-		configPath: configPath,
-		binaryPath: binaryPath,
-		scrubPlan:  scrubPlan,
-		scrubOlder: scrubOlder,
-		logger:     logger,
+		configPath:  opts.ConfigPath,
+		binaryPath:  opts.BinaryPath,
+		scrubPlan:   opts.ScrubPlan,
+		scrubOlder:  opts.ScrubOlderThan,
+		diffLimit:   opts.Diff.MaxPaths,
+		spillDir:    opts.Diff.SpillDir,
+		filters:     opts.Filters,
+		quiet:       opts.Command.Quiet,
+		extraArgs:   opts.Command.ExtraArgs,
+		gracePeriod: opts.GracePeriod,
+		logger:      logger,
+
+		progressInterval: opts.ProgressInterval,
+		onProgress: func(p Progress) {
+			if r.OnProgress != nil {
+				r.OnProgress(p)
+			}
+		},
 	}
 	return r
 }
 
-// Run executes the SnapRAID workflow in this order: Touch → Diff → (Sync → Scrub → Smart).
+// Run executes the SnapRAID workflow in this order: Touch → Diff → (Sync → Verify → Scrub → Smart → Status → Repair).
 // It returns a RunResult containing timestamps, parsed diff, per‐step durations, and any error.
-func (r *Runner) Run() RunResult {
+// Cancelling ctx interrupts the running step; the result then reports ErrorKindCancelled.
+// Exceeding a step timeout or the total budget reports ErrorKindTimeout.
+func (r *Runner) Run(ctx context.Context) RunResult {
 	now := time.Now()
 
 	r.Timestamp = now
 	runResult := RunResult{
 		Timestamp: r.Timestamp.Format(time.RFC3339),
+		Filters:   r.Filters.active(),
 	}
 
 	// Always record the total time, even if there is an error
@@ -96,60 +270,270 @@ func (r *Runner) Run() RunResult {
 		runResult.Timings.Total = time.Since(start)
 	}()
 
+	// Enforce the total run budget
+	if r.Timeouts.Total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, r.Timeouts.Total, &TimeoutError{Limit: r.Timeouts.Total})
+		defer cancel()
+	}
+
 	// TOUCH - makes only sense if it is not a dry run
 	if r.Steps.Touch && !r.DryRun {
-		if err := runStep(r.exec.Touch, func(d time.Duration) { runResult.Timings.Touch = d }); err != nil {
-			runResult.Error = err
+		if err := runStep(ctx, r.step(&runResult, "touch", r.exec.Touch), func(d time.Duration) { runResult.Timings.Touch = d }); err != nil {
+			runResult.setError("touch", err)
 			return runResult
 		}
 	}
 
 	// DIFF
-	t1 := time.Now()
-	diffLines, err := r.exec.Diff()
-	runResult.Timings.Diff = time.Since(t1)
-	if err != nil {
-		runResult.Error = err
+	var diffResult DiffResult
+	diffStep := func(ctx context.Context) error {
+		var err error
+		diffResult, err = r.exec.Diff(ctx)
+		return err
+	}
+	if err := runStep(ctx, r.step(&runResult, "diff", diffStep), func(d time.Duration) { runResult.Timings.Diff = d }); err != nil {
+		runResult.setError("diff", err)
+		return runResult
+	}
+
+	// SETTLE - files still being written show up differently in a repeated diff
+	settled := true
+	if r.Settle.Interval > 0 && !r.DryRun && diffResult.HasChanges() {
+		var err error
+		diffResult, settled, err = r.settle(ctx, &runResult, diffResult)
+		if err != nil {
+			runResult.setError("diff", err)
+			return runResult
+		}
+	}
+
+	// Thresholds computed on a partially parsed diff would be wrong
+	if err := diffResult.verifyCounts(); err != nil {
+		runResult.Result = diffResult
+		errors.As(err, &runResult.Mismatches)
+		runResult.setError("diff", err)
 		return runResult
 	}
 
-	diffResult := parseDiff(diffLines)
+	// Set aside entries that must not count against the thresholds
+	diffResult, ignored := splitIgnored(diffResult, r.Thresholds.Ignore)
+	diffResult = filterDiff(diffResult, r.Filters.Diff)
 	runResult.Result = diffResult
+	if ignored.HasChanges() {
+		runResult.Ignored = &ignored
+	}
+	for _, w := range diffResult.Warnings {
+		r.log().Warn("SnapRAID diff warning", "kind", w.Kind, "disk", w.Disk, "message", w.Message, "tag", "diff")
+	}
 
 	// DRY RUN? skip Sync/Scrub/Smart if true
 	if r.DryRun {
 		return runResult
 	}
 
-	if diffResult.HasChanges() {
-		// THRESHOLD CHECK
-		if err := validateThresholds(diffResult, r.Thresholds); err != nil {
-			runResult.Error = err
-			return runResult
+	// Data that is still changing is synced by a later run
+	if !settled {
+		runResult.Deferred = true
+		r.log().Warn("Changes did not settle, deferring sync", "checks", runResult.SettleChecks, "tag", "settle")
+		return runResult
+	}
+
+	// A filtered diff does not show the whole array, so it cannot release a sync.
+	// A missing or empty disk must fail the gate even if it produced no file changes.
+	if r.Filters.Diff.active() {
+		r.log().Warn("Diff filter active, skipping sync", "tag", "sync")
+	} else if runResult.HasChanges() || len(diffResult.BlockingWarnings()) > 0 {
+		// THRESHOLD CHECK - allowlisted empty disks are left to the force policy of sync
+		gated := r.Force.exempt(diffResult)
+		if err := validateThresholds(gated, r.Thresholds); err != nil {
+			errors.As(err, &runResult.Violations)
+			if !r.consumeApproval(&runResult, gated) {
+				runResult.Fingerprint = gated.Fingerprint()
+				runResult.setError("thresholds", err)
+				return runResult
+			}
 		}
 
 		// SYNC
-		if err := runStep(r.exec.Sync, func(d time.Duration) { runResult.Timings.Sync = d }); err != nil {
-			runResult.Error = err
+		if err := r.sync(ctx, &runResult); err != nil {
+			runResult.setError("sync", err)
 			return runResult
 		}
+
+		// VERIFY - optional, files written while sync was running are not fully protected
+		if r.Steps.Verify {
+			var verifyResult DiffResult
+			verifyStep := func(ctx context.Context) error {
+				var err error
+				verifyResult, err = r.exec.Diff(ctx)
+				return err
+			}
+			if err := runStep(ctx, r.step(&runResult, "verify", verifyStep), func(d time.Duration) { runResult.Timings.Verify = d }); err != nil {
+				runResult.setError("verify", err)
+				return runResult
+			}
+			if verifyResult.HasChanges() {
+				runResult.ChangedDuringSync = &verifyResult
+				r.log().Warn("Files changed during sync", "changes", verifyResult.Changes(), "tag", "verify")
+			}
+		}
 	}
 
-	// SCRUB
+	// SCRUB - optional, skipped if its timeout no longer fits into the total budget
+	var scrubErr error
 	if r.Steps.Scrub {
-		if err := runStep(r.exec.Scrub, func(d time.Duration) { runResult.Timings.Scrub = d }); err != nil {
-			runResult.Error = err
-			return runResult
+		if lacksBudget(ctx, r.Timeouts.Scrub) {
+			r.skip(&runResult, "scrub")
+		} else if err := r.scrub(ctx, &runResult); err != nil {
+			// Errors found by scrub are left to the repair policy
+			if !r.repairs(&runResult, err) {
+				runResult.setError("scrub", err)
+				return runResult
+			}
+			scrubErr = err
 		}
 	}
 
-	// SMART
+	// SMART - optional, skipped if its timeout no longer fits into the total budget
 	if r.Steps.Smart {
-		if err := runStep(r.exec.Smart, func(d time.Duration) { runResult.Timings.Smart = d }); err != nil {
-			runResult.Error = err
+		if lacksBudget(ctx, r.Timeouts.Smart) {
+			r.skip(&runResult, "smart")
+		} else if err := runStep(ctx, r.step(&runResult, "smart", resultStep(r.exec.Smart, &runResult.Smart)), func(d time.Duration) { runResult.Timings.Smart = d }); err != nil {
+			runResult.setError("smart", err)
+			return runResult
+		}
+	}
+
+	// STATUS - optional, skipped if its timeout no longer fits into the total budget
+	if r.Steps.Status {
+		if lacksBudget(ctx, r.Timeouts.Status) {
+			r.skip(&runResult, "status")
+		} else if err := runStep(ctx, r.step(&runResult, "status", resultStep(r.exec.Status, &runResult.Status)), func(d time.Duration) { runResult.Timings.Status = d }); err != nil {
+			runResult.setError("status", err)
 			return runResult
 		}
 	}
 
+	// REPAIR - optional, handles blocks with errors found by scrub or status
+	if r.Repair.enabled() {
+		if trigger, errs := repairTrigger(runResult); trigger != "" {
+			if err := r.repair(ctx, &runResult, trigger, errs); err != nil {
+				return runResult
+			}
+		}
+	}
+
+	// A scrub that found errors still fails the run unless the repair was confirmed
+	if scrubErr != nil && !runResult.Repair.Confirmed() {
+		runResult.setError("scrub", scrubErr)
+	}
+
 	return runResult
 }
+
+// scrub runs the scrub step, with the plan computed by the scrub planner if it is enabled.
+func (r *Runner) scrub(ctx context.Context, res *RunResult) error {
+	plan, olderThan := "", 0
+	if r.Planner.enabled() {
+		decision := r.planScrub(ctx, res)
+		res.ScrubPlan = &decision
+		if decision.Skip {
+			r.log().Warn("Skipping scrub, not even 1% of the array fits the maximum scrub duration", "reason", decision.Reason, "tag", "scrub")
+			return nil
+		}
+		plan, olderThan = decision.Plan, decision.OlderThan
+	}
+	scrub := func(ctx context.Context) (*Summary, error) { return r.exec.Scrub(ctx, plan, olderThan) }
+	return runStep(ctx, r.step(res, "scrub", resultStep(scrub, &res.Scrub)), func(d time.Duration) { res.Timings.Scrub = d })
+}
+
+// settle repeats diff after the settle interval until two consecutive results are identical.
+// It returns the latest result and false if it still changed after Settle.Attempts repetitions.
+func (r *Runner) settle(ctx context.Context, res *RunResult, prev DiffResult) (DiffResult, bool, error) {
+	for check := 1; check <= r.Settle.Attempts; check++ {
+		select {
+		case <-ctx.Done():
+			return prev, false, context.Cause(ctx)
+		case <-time.After(r.Settle.Interval):
+		}
+
+		var next DiffResult
+		diffStep := func(ctx context.Context) error {
+			var err error
+			next, err = r.exec.Diff(ctx)
+			return err
+		}
+		if err := runStep(ctx, r.step(res, "diff", diffStep), func(d time.Duration) { res.Timings.Diff += d }); err != nil {
+			return prev, false, err
+		}
+		res.SettleChecks = check
+
+		// Only the latest result is kept, so is its spill file
+		if prev.SpillFile != "" && prev.SpillFile != next.SpillFile {
+			os.Remove(prev.SpillFile) // nolint:errcheck
+		}
+
+		if next.Fingerprint() == prev.Fingerprint() {
+			return next, true, nil
+		}
+		r.log().Info("Changes are still settling", "check", check, "changes", next.Changes(), "tag", "settle")
+		prev = next
+	}
+	return prev, false, nil
+}
+
+// step wraps fn with the timeout and retry policy of the named step and records every attempt in res.
+func (r *Runner) step(res *RunResult, name string, fn func(context.Context) error) func(context.Context) error {
+	fn = withTimeout(name, r.Timeouts.forStep(name), fn)
+	return func(ctx context.Context) error {
+		return runWithRetry(ctx, name, r.Retries.forStep(name), r.log(), fn, func(a Attempt) {
+			res.Attempts = append(res.Attempts, a)
+		})
+	}
+}
+
+// consumeApproval releases a blocked sync if the pending approval matches the gated diff within
+// the configured tolerance. A matching approval is removed so it releases only one run.
+func (r *Runner) consumeApproval(res *RunResult, diff DiffResult) bool {
+	if r.outputDir == "" {
+		return false
+	}
+
+	approval, err := loadApproval(r.outputDir)
+	if err != nil {
+		r.log().Warn("Ignoring pending approval", "error", err, "tag", "approval")
+		return false
+	}
+	if approval == nil {
+		return false
+	}
+	// diff is what the gate saw, so the approved diff must not count exempted empty disks either
+	approval.Result = r.Force.exempt(approval.Result)
+	if !approval.matches(diff, r.Tolerance) {
+		r.log().Info("Diff does not match the approved run", "approved_run", approval.Timestamp, "tag", "approval")
+		return false
+	}
+	if err := removeApproval(r.outputDir); err != nil {
+		r.log().Warn("Ignoring pending approval", "error", err, "tag", "approval")
+		return false
+	}
+
+	res.ApprovedRun = approval.Timestamp
+	r.log().Info("Threshold violations approved, continuing with sync", "approved_run", approval.Timestamp, "tag", "approval")
+	return true
+}
+
+// skip records that an optional step was not started because the time budget was nearly used up.
+func (r *Runner) skip(res *RunResult, step string) {
+	res.Skipped = append(res.Skipped, step)
+	r.log().Warn("Skipping step, remaining time budget is too short", "step", step, "tag", "runner")
+}
+
+// log returns the runner's logger, or a logger that discards everything if none is set.
+func (r *Runner) log() *slog.Logger {
+	if r.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return r.Logger
+}
@@ -1,9 +1,11 @@
 package snapraid
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -13,44 +15,126 @@ import (
 
 // fakeExec allows simulating different Snapraid behaviors.
 type fakeExec struct {
-	DiffLines []string // DiffLines to return from Diff()
-	DiffErr   error    // DiffErr simulates an error from Diff()
-	TouchErr  error    // TouchErr simulates an error from Touch()
-	SyncErr   error    // SyncErr simulates an error from Sync()
-	ScrubErr  error    // ScrubErr simulates an error from Scrub()
-	SmartErr  error    // SmartErr simulates an error from Smart()
+	DiffLines []string      // DiffLines to return from Diff()
+	DiffSeq   [][]string    // DiffSeq, if set, is returned by consecutive Diff() calls, repeating the last one
+	DiffErr   error         // DiffErr simulates an error from Diff()
+	TouchErr  error         // TouchErr simulates an error from Touch()
+	SyncErr   error         // SyncErr simulates an error from Sync()
+	Summary   *Summary      // Summary is returned from Sync() and Scrub()
+	ScrubErr  error         // ScrubErr simulates an error from Scrub()
+	SmartErr  error         // SmartErr simulates an error from Smart()
+	Report    *SmartReport  // Report is returned from Smart()
+	StatusErr error         // StatusErr simulates an error from Status()
+	Array     *StatusReport // Array is returned from Status()
+	Blocking  string        // Blocking names a step that blocks until its context is done
+	SyncFails int           // SyncFails makes the first N Sync calls fail with SyncErr
+	SyncSeq   []error       // SyncSeq, if set, is returned by consecutive Sync() calls, succeeding after the last one
+	Fixed     *Summary      // Fixed is returned from Fix() and Check()
+	FixErr    error         // FixErr simulates an error from Fix() and Check()
+	Rescrub   *Summary      // Rescrub is returned from Scrub() with the "bad" plan
+	Plans     []string      // Plans records the plan of every Scrub() call
+	OlderThan []int         // OlderThan records the older-than days of every Scrub() call
+	Forces    [][]string    // Forces records the force options of every Sync() call
+	Disks     []DataDisk    // Disks, if set, attribute the entries returned by Diff() to data disks
 
 	// Counters to verify calls
-	TouchCount int
-	DiffCount  int
-	SyncCount  int
-	ScrubCount int
-	SmartCount int
+	TouchCount  int
+	DiffCount   int
+	SyncCount   int
+	ScrubCount  int
+	SmartCount  int
+	StatusCount int
+	FixCount    int
+	CheckCount  int
 }
 
-func (f *fakeExec) Touch() error {
+func (f *fakeExec) Status(ctx context.Context) (*StatusReport, error) {
+	f.StatusCount++
+	if err := f.block(ctx, "status"); err != nil {
+		return nil, err
+	}
+	return f.Array, f.StatusErr
+}
+
+// block waits for ctx if step is the blocking step and returns the cancellation cause.
+func (f *fakeExec) block(ctx context.Context, step string) error {
+	if f.Blocking != step {
+		return nil
+	}
+	<-ctx.Done()
+	return fmt.Errorf("snapraid %s failed: %w", step, context.Cause(ctx))
+}
+
+func (f *fakeExec) Touch(ctx context.Context) error {
 	f.TouchCount++
+	if err := f.block(ctx, "touch"); err != nil {
+		return err
+	}
 	return f.TouchErr
 }
 
-func (f *fakeExec) Diff() ([]string, error) {
+func (f *fakeExec) Diff(ctx context.Context) (DiffResult, error) {
 	f.DiffCount++
-	return f.DiffLines, f.DiffErr
+	if err := f.block(ctx, "diff"); err != nil {
+		return DiffResult{}, err
+	}
+	if len(f.DiffSeq) > 0 {
+		return parseDiff(f.DiffSeq[min(f.DiffCount, len(f.DiffSeq))-1]), f.DiffErr
+	}
+	res := parseDiff(f.DiffLines)
+	if f.Disks != nil {
+		res.resolveDisks(f.Disks)
+	}
+	return res, f.DiffErr
 }
 
-func (f *fakeExec) Sync() error {
+func (f *fakeExec) Sync(ctx context.Context, force []string) (*Summary, error) {
 	f.SyncCount++
-	return f.SyncErr
+	f.Forces = append(f.Forces, force)
+	if err := f.block(ctx, "sync"); err != nil {
+		return nil, err
+	}
+	if len(f.SyncSeq) > 0 {
+		if f.SyncCount <= len(f.SyncSeq) {
+			return f.Summary, f.SyncSeq[f.SyncCount-1]
+		}
+		return f.Summary, nil
+	}
+	if f.SyncFails > 0 && f.SyncCount > f.SyncFails {
+		return f.Summary, nil
+	}
+	return f.Summary, f.SyncErr
 }
 
-func (f *fakeExec) Scrub() error {
+func (f *fakeExec) Scrub(ctx context.Context, plan string, olderThan int) (*Summary, error) {
 	f.ScrubCount++
-	return f.ScrubErr
+	f.Plans = append(f.Plans, plan)
+	f.OlderThan = append(f.OlderThan, olderThan)
+	if err := f.block(ctx, "scrub"); err != nil {
+		return nil, err
+	}
+	if plan == "bad" {
+		return f.Rescrub, nil
+	}
+	return f.Summary, f.ScrubErr
+}
+
+func (f *fakeExec) Fix(ctx context.Context) (*Summary, error) {
+	f.FixCount++
+	return f.Fixed, f.FixErr
+}
+
+func (f *fakeExec) Check(ctx context.Context) (*Summary, error) {
+	f.CheckCount++
+	return f.Fixed, f.FixErr
 }
 
-func (f *fakeExec) Smart() error {
+func (f *fakeExec) Smart(ctx context.Context) (*SmartReport, error) {
 	f.SmartCount++
-	return f.SmartErr
+	if err := f.block(ctx, "smart"); err != nil {
+		return nil, err
+	}
+	return f.Report, f.SmartErr
 }
 
 func TestNewRunner(t *testing.T) {
@@ -60,30 +144,54 @@ func TestNewRunner(t *testing.T) {
 		configPath    = "/etc/snapraid.conf"
 		binaryPath    = "/usr/bin/snapraid"
 		outputPath    = "/var/log/snapraid"
-		scrubPlanVal  = 25
+		scrubPlanVal  = "25"
 		scrubOlderVal = 7
+		graceVal      = 30 * time.Second
+		progressVal   = 10 * time.Second
 	)
 
 	steps := Steps{Touch: true, Scrub: false, Smart: true}
 	thresholds := Thresholds{Add: 10, Remove: 20, Update: 30, Move: 40, Copy: 50, Restore: 60}
+	timeouts := Timeouts{Sync: time.Hour, Total: 2 * time.Hour}
+	retries := Retries{Sync: RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}}
 	dryRun := true
 	logger := slog.New(slog.NewTextHandler(nil, nil))
 
-	r := NewRunner(
-		configPath,
-		binaryPath,
-		outputPath,
-		steps,
-		thresholds,
-		scrubPlanVal,
-		scrubOlderVal,
-		dryRun,
-		logger,
-	)
+	r := NewRunner(RunnerOptions{
+		ConfigPath:       configPath,
+		BinaryPath:       binaryPath,
+		OutputDir:        outputPath,
+		Steps:            steps,
+		Thresholds:       thresholds,
+		Tolerance:        3,
+		Timeouts:         timeouts,
+		Retries:          retries,
+		Diff:             DiffOptions{MaxPaths: 100, SpillDir: "/tmp"},
+		Settle:           Settle{Interval: time.Minute, Attempts: 2},
+		Repair:           RepairFix,
+		Planner:          ScrubPlanner{TargetDays: 30, MaxDuration: time.Hour},
+		Filters:          Filters{Check: Filter{Disks: []string{"d2"}}},
+		Command:          CommandOptions{Quiet: true, ExtraArgs: map[string][]string{"sync": {"--pre-hash"}}},
+		Force:            ForcePolicy{ZeroSize: []string{"*.lock"}, EmptyDisks: []string{"d3"}},
+		ScrubPlan:        scrubPlanVal,
+		ScrubOlderThan:   scrubOlderVal,
+		GracePeriod:      graceVal,
+		ProgressInterval: progressVal,
+		DryRun:           dryRun,
+	}, logger)
 
 	// Runner fields
 	assert.Equal(t, steps, r.Steps, "Steps should match")
 	assert.Equal(t, thresholds, r.Thresholds, "Thresholds should match")
+	assert.Equal(t, 3, r.Tolerance, "Tolerance should match")
+	assert.Equal(t, Settle{Interval: time.Minute, Attempts: 2}, r.Settle, "Settle should match")
+	assert.Equal(t, RepairFix, r.Repair, "Repair should match")
+	assert.Equal(t, ScrubPlanner{TargetDays: 30, MaxDuration: time.Hour}, r.Planner, "Planner should match")
+	assert.Equal(t, Filters{Check: Filter{Disks: []string{"d2"}}}, r.Filters, "Filters should match")
+	assert.Equal(t, ForcePolicy{ZeroSize: []string{"*.lock"}, EmptyDisks: []string{"d3"}}, r.Force, "Force should match")
+	assert.Equal(t, outputPath, r.outputDir, "outputDir should match")
+	assert.Equal(t, timeouts, r.Timeouts, "Timeouts should match")
+	assert.Equal(t, retries, r.Retries, "Retries should match")
 	assert.Equal(t, dryRun, r.DryRun, "DryRun should match")
 	assert.Equal(t, logger, r.Logger, "Logger should match")
 
@@ -95,6 +203,13 @@ func TestNewRunner(t *testing.T) {
 	assert.Equal(t, binaryPath, de.binaryPath, "DefaultExecutor.binaryPath should match")
 	assert.Equal(t, scrubPlanVal, de.scrubPlan, "DefaultExecutor.scrubPlan should match")
 	assert.Equal(t, scrubOlderVal, de.scrubOlder, "DefaultExecutor.scrubOlder should match")
+	assert.Equal(t, 100, de.diffLimit, "DefaultExecutor.diffLimit should match")
+	assert.Equal(t, "/tmp", de.spillDir, "DefaultExecutor.spillDir should match")
+	assert.Equal(t, Filters{Check: Filter{Disks: []string{"d2"}}}, de.filters, "DefaultExecutor.filters should match")
+	assert.True(t, de.quiet, "DefaultExecutor.quiet should match")
+	assert.Equal(t, map[string][]string{"sync": {"--pre-hash"}}, de.extraArgs, "DefaultExecutor.extraArgs should match")
+	assert.Equal(t, graceVal, de.gracePeriod, "DefaultExecutor.gracePeriod should match")
+	assert.Equal(t, progressVal, de.progressInterval, "DefaultExecutor.progressInterval should match")
 	assert.Equal(t, logger, de.logger, "DefaultExecutor.logger should match")
 }
 
@@ -141,7 +256,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Should have run Diff once, not Sync/Scrub/Smart
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
@@ -151,6 +266,45 @@ There are differences!
 		assert.False(t, result.HasChanges(), "Result should report no changes")
 	})
 
+	t.Run("Active filters are recorded", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"1 equal"}}
+		r := &Runner{
+			Steps:   Steps{Scrub: true},
+			Filters: Filters{Check: Filter{Disks: []string{"d2"}}},
+			exec:    f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, map[string]Filter{"check": {Disks: []string{"d2"}}}, result.Filters)
+	})
+
+	t.Run("Diff filter narrows the result and skips sync", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"remove /mnt/d1/a.txt", "remove /mnt/d2/b.txt", "remove /mnt/d2/c.txt"},
+			Disks:     []DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d2", Dir: "/mnt/d2/"}},
+		}
+		r := &Runner{
+			Steps:      Steps{Smart: true},
+			Thresholds: Thresholds{Add: -1, Remove: 1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+			Filters:    Filters{Diff: Filter{Disks: []string{"d1"}}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []string{"/mnt/d1/a.txt"}, result.Result.Removed)
+		assert.Empty(t, result.Violations)
+		assert.Equal(t, 0, f.SyncCount, "A filtered diff never releases a sync")
+		assert.Equal(t, 1, f.SmartCount, "Later steps still run")
+	})
+
 	t.Run("No threshold violation", func(t *testing.T) {
 		t.Parallel()
 
@@ -165,7 +319,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Should have run Diff, then returned error before Sync
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
@@ -193,7 +347,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Should have run Diff, then returned error before Sync
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
@@ -215,7 +369,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Touch should run (because DryRun=false? Actually code: Touch runs only if !DryRun, so DryRun skips Touch too)
 		assert.Equal(t, 0, f.TouchCount, "Touch should be skipped on DryRun")
@@ -242,7 +396,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		assert.Equal(t, 1, f.TouchCount, "Touch should be called once")
 		assert.Equal(t, 1, len(result.Result.Added), fmt.Sprintf("Result.Added should be 1, got %d", len(result.Result.Added)))
@@ -265,7 +419,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		assert.Empty(t, f.DiffCount, "Diff should be called once")
 		assert.Empty(t, f.SyncCount, "Sync should be called once")
@@ -288,7 +442,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
 		assert.Error(t, result.Error, "Error should be set for diff failure")
@@ -312,7 +466,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
 		assert.Equal(t, 1, f.SyncCount, "Sync should be called once")
@@ -337,7 +491,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Should call Diff, Sync, then Scrub, then stop
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
@@ -365,7 +519,7 @@ There are differences!
 			Timestamp:  time.Now(),
 		}
 
-		result := r.Run()
+		result := r.Run(context.Background())
 
 		// Should call Diff, Sync, Scrub, then Smart, then stop
 		assert.Equal(t, 1, f.DiffCount, "Diff should be called once")
@@ -376,3 +530,1180 @@ There are differences!
 		assert.EqualError(t, result.Error, "smart failed")
 	})
 }
+
+func TestRunnerCancel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Cancelled step is recorded", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   fmt.Errorf("snapraid sync failed: %w", context.Canceled),
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true, Smart: true},
+			Thresholds: Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.True(t, result.Cancelled())
+		assert.Equal(t, ErrorKindCancelled, result.ErrorKind)
+		assert.Equal(t, "sync", result.FailedStep)
+		assert.Equal(t, 0, f.ScrubCount, "Scrub should not run after cancellation")
+	})
+
+	t.Run("Already cancelled context skips all steps", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Steps: Steps{Touch: true, Scrub: true, Smart: true},
+			exec:  f,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result := r.Run(ctx)
+
+		assert.Equal(t, 0, f.TouchCount)
+		assert.Equal(t, 0, f.DiffCount)
+		assert.True(t, result.Cancelled())
+		assert.Equal(t, "touch", result.FailedStep)
+	})
+
+	t.Run("Plain errors are classified as failed", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffErr: errors.New("diff failed")}
+		r := &Runner{exec: f}
+
+		result := r.Run(context.Background())
+
+		assert.False(t, result.Cancelled())
+		assert.Equal(t, ErrorKindFailed, result.ErrorKind)
+		assert.Equal(t, "diff", result.FailedStep)
+	})
+}
+
+func TestRunnerTimeouts(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+
+	t.Run("Step timeout is reported as timeout", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}, Blocking: "sync"}
+		r := &Runner{
+			Steps:      Steps{Scrub: true},
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Sync: 50 * time.Millisecond},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.True(t, result.TimedOut())
+		assert.Equal(t, "sync", result.FailedStep)
+		assert.EqualError(t, result.Error, "snapraid sync failed: sync timed out after 50ms")
+		assert.Equal(t, 0, f.ScrubCount, "Scrub should not run after a timeout")
+	})
+
+	t.Run("Total budget is reported as timeout", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}, Blocking: "sync"}
+		r := &Runner{
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Total: 50 * time.Millisecond},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.True(t, result.TimedOut())
+		assert.Equal(t, "sync", result.FailedStep)
+		assert.EqualError(t, result.Error, "snapraid sync failed: run exceeded total time budget of 50ms")
+	})
+
+	t.Run("Optional steps are skipped when budget is short", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Steps:      Steps{Scrub: true, Smart: true},
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Scrub: 2 * time.Hour, Smart: time.Minute, Total: time.Hour},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 1, f.SyncCount, "Sync should run")
+		assert.Equal(t, 0, f.ScrubCount, "Scrub should be skipped")
+		assert.Equal(t, 1, f.SmartCount, "Smart should still fit into the budget")
+		assert.Equal(t, []string{"scrub"}, result.Skipped)
+	})
+
+	t.Run("Optional steps without a timeout need a minimum budget", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Steps:      Steps{Scrub: true, Smart: true, Status: true},
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Total: 30 * time.Second},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 1, f.SyncCount, "Sync should run")
+		assert.Equal(t, 0, f.ScrubCount+f.SmartCount+f.StatusCount, "No optional step should start")
+		assert.Equal(t, []string{"scrub", "smart", "status"}, result.Skipped)
+	})
+}
+
+func TestRunnerRetries(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+	locked := &CommandError{Cmd: "sync", Stderr: "Content file '/a' is locked", Err: errors.New("exit status 1")}
+	transient := []*regexp.Regexp{regexp.MustCompile("locked")}
+
+	t.Run("Transient sync failure is retried", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   locked,
+			SyncFails: 2,
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Retries:    Retries{Sync: RetryPolicy{MaxAttempts: 3, StderrPatterns: transient}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 3, f.SyncCount)
+		assert.Equal(t, 3, result.AttemptsFor("sync"))
+		assert.Equal(t, 1, result.AttemptsFor("diff"))
+		assert.Equal(t, locked.Error(), result.Attempts[1].Error)
+		assert.Empty(t, result.Attempts[3].Error)
+	})
+
+	t.Run("Exhausted retries report the last error", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   locked,
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Retries:    Retries{Sync: RetryPolicy{MaxAttempts: 2, StderrPatterns: transient}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.ErrorIs(t, result.Error, locked)
+		assert.Equal(t, 2, f.SyncCount)
+		assert.Equal(t, 2, result.AttemptsFor("sync"))
+	})
+
+	t.Run("Failures without exit codes or patterns are not retried", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   locked,
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Retries:    Retries{Sync: RetryPolicy{MaxAttempts: 3}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.ErrorIs(t, result.Error, locked)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+}
+
+func TestRunnerSummaries(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeExec{
+		DiffLines: []string{"add a.txt"},
+		Summary:   &Summary{Exit: "ok", DataErrors: 2},
+		Report:    &SmartReport{Disks: []SmartDisk{{Disk: "d1", Device: "/dev/sdb"}}},
+		Array:     &StatusReport{Disks: []StatusDisk{{Name: "d1", Files: 10}}},
+	}
+	r := &Runner{
+		Steps:      Steps{Scrub: true, Smart: true, Status: true},
+		Thresholds: Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+		exec:       f,
+	}
+
+	result := r.Run(context.Background())
+
+	assert.NoError(t, result.Error)
+	assert.Equal(t, f.Summary, result.Sync)
+	assert.Equal(t, f.Summary, result.Scrub)
+	assert.True(t, result.Scrub.HasErrors())
+	assert.Equal(t, f.Report, result.Smart)
+	assert.Equal(t, f.Array, result.Status)
+}
+
+func TestRunnerMissingDisk(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeExec{
+		DiffLines: []string{"WARNING! All the files previously present in disk 'd1' at dir '/mnt/disk1/'"},
+	}
+	r := &Runner{
+		Thresholds: Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+		exec:       f,
+	}
+
+	result := r.Run(context.Background())
+
+	assert.EqualError(t, result.Error, "all files on disk d1 at /mnt/disk1/ are missing")
+	assert.Equal(t, "thresholds", result.FailedStep)
+	assert.Equal(t, ErrorKindThreshold, result.ErrorKind)
+	assert.Equal(t, ThresholdViolations{{Category: "missing_disk", Disk: "d1", Dir: "/mnt/disk1/"}}, result.Violations)
+	assert.Equal(t, 0, f.SyncCount, "Sync must not run when a disk is missing")
+	assert.Len(t, result.Result.Warnings, 1)
+}
+
+func TestRunnerStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Status runs without changes", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{Array: &StatusReport{SilentErrors: 1}}
+		r := &Runner{Steps: Steps{Status: true}, exec: f}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 0, f.SyncCount)
+		assert.Equal(t, 1, f.StatusCount)
+		assert.False(t, result.Status.Healthy())
+	})
+
+	t.Run("Status error is reported", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{StatusErr: errors.New("status failed")}
+		r := &Runner{Steps: Steps{Status: true}, exec: f}
+
+		result := r.Run(context.Background())
+
+		assert.EqualError(t, result.Error, "status failed")
+		assert.Equal(t, "status", result.FailedStep)
+		assert.Nil(t, result.Status)
+	})
+
+	t.Run("Dry run skips status", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{}
+		r := &Runner{Steps: Steps{Status: true}, DryRun: true, exec: f}
+
+		r.Run(context.Background())
+
+		assert.Equal(t, 0, f.StatusCount)
+	})
+}
+
+func TestRunnerApproval(t *testing.T) {
+	t.Parallel()
+
+	blocked := func(dir string) (*Runner, *fakeExec) {
+		f := &fakeExec{DiffLines: []string{"remove a.txt", "remove b.txt"}}
+		return &Runner{
+			Thresholds: Thresholds{Add: -1, Remove: 1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+			outputDir:  dir,
+			exec:       f,
+		}, f
+	}
+
+	t.Run("Blocked run records fingerprint", func(t *testing.T) {
+		t.Parallel()
+
+		r, f := blocked(t.TempDir())
+		result := r.Run(context.Background())
+
+		assert.Equal(t, ErrorKindThreshold, result.ErrorKind)
+		assert.Equal(t, result.Result.Fingerprint(), result.Fingerprint)
+		assert.Empty(t, result.ApprovedRun)
+		assert.Equal(t, 0, f.SyncCount)
+	})
+
+	t.Run("Approved run syncs once", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		r, f := blocked(dir)
+		first := r.Run(context.Background())
+		first.Timestamp = "2025-06-01T03:00:00Z"
+		assert.NoError(t, first.WriteJSON(dir))
+
+		_, err := Approve(dir, first.Timestamp)
+		assert.NoError(t, err)
+
+		second := r.Run(context.Background())
+		assert.NoError(t, second.Error)
+		assert.Equal(t, first.Timestamp, second.ApprovedRun)
+		assert.Len(t, second.Violations, 1, "approved violations are still reported")
+		assert.Empty(t, second.Fingerprint)
+		assert.Equal(t, 1, f.SyncCount)
+
+		third := r.Run(context.Background())
+		assert.Equal(t, ErrorKindThreshold, third.ErrorKind, "an approval releases only one run")
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("Changed diff stays blocked", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		r, f := blocked(dir)
+		first := r.Run(context.Background())
+		first.Timestamp = "2025-06-01T03:00:00Z"
+		assert.NoError(t, first.WriteJSON(dir))
+		_, err := Approve(dir, first.Timestamp)
+		assert.NoError(t, err)
+
+		f.DiffLines = append(f.DiffLines, "remove c.txt")
+		second := r.Run(context.Background())
+		assert.Equal(t, ErrorKindThreshold, second.ErrorKind)
+		assert.Equal(t, 0, f.SyncCount)
+
+		r.Tolerance = 1
+		third := r.Run(context.Background())
+		assert.NoError(t, third.Error)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("Allowlisted empty disk matches within tolerance", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		f := &fakeExec{
+			DiffLines: []string{
+				"add /mnt/d1/a.txt",
+				"add /mnt/d1/b.txt",
+				"remove /mnt/d3/c.txt",
+				"All the files previously present in disk 'd3' at dir '/mnt/d3/'",
+			},
+			Disks: []DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d3", Dir: "/mnt/d3/"}},
+		}
+		r := &Runner{
+			Thresholds: Thresholds{Add: 1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+			Force:      ForcePolicy{EmptyDisks: []string{"d3"}},
+			Tolerance:  5,
+			outputDir:  dir,
+			exec:       f,
+		}
+		first := r.Run(context.Background())
+		assert.Equal(t, ErrorKindThreshold, first.ErrorKind)
+		first.Timestamp = "2025-06-01T03:00:00Z"
+		assert.NoError(t, first.WriteJSON(dir))
+		_, err := Approve(dir, first.Timestamp)
+		assert.NoError(t, err)
+
+		f.DiffLines = append(f.DiffLines, "add /mnt/d1/d.txt")
+		second := r.Run(context.Background())
+		assert.NoError(t, second.Error)
+		assert.Equal(t, first.Timestamp, second.ApprovedRun)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+}
+
+func TestRunnerIgnore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Ignored entries do not count against thresholds", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"remove .DS_Store", "remove photos/Thumbs.db", "remove a.txt"}}
+		r := &Runner{
+			Thresholds: Thresholds{Add: -1, Remove: 1, Update: -1, Move: -1, Copy: -1, Restore: -1, Ignore: []string{".DS_Store", "Thumbs.db"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []string{"a.txt"}, result.Result.Removed)
+		assert.Equal(t, []string{".DS_Store", "photos/Thumbs.db"}, result.Ignored.Removed)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("Only ignored changes still sync", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add movies/a.nfo"}}
+		r := &Runner{
+			Thresholds: Thresholds{Add: 0, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1, Ignore: []string{"*.nfo"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.False(t, result.Result.HasChanges())
+		assert.True(t, result.HasChanges())
+		assert.Equal(t, 1, f.SyncCount)
+	})
+}
+
+func TestRunnerParseIntegrity(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeExec{DiffLines: []string{"add a.txt", "1 added", "2 removed"}}
+	r := &Runner{
+		Thresholds: Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1},
+		exec:       f,
+	}
+
+	result := r.Run(context.Background())
+
+	assert.Equal(t, ErrorKindParseIntegrity, result.ErrorKind)
+	assert.Equal(t, "diff", result.FailedStep)
+	assert.Equal(t, ParseIntegrityError{{Category: "removed", Reported: 2, Parsed: 0}}, result.Mismatches)
+	assert.Equal(t, []string{"a.txt"}, result.Result.Added)
+	assert.Equal(t, 0, f.SyncCount, "Sync must not run on a partially parsed diff")
+}
+
+func TestRunnerSettle(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+
+	t.Run("Identical diffs sync", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Thresholds: noLimits,
+			Settle:     Settle{Interval: time.Millisecond, Attempts: 3},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.False(t, result.Deferred)
+		assert.Equal(t, 1, result.SettleChecks)
+		assert.Equal(t, 2, f.DiffCount)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("Changes settle after a retry", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffSeq: [][]string{
+			{"add a.part"},
+			{"add a.mkv"},
+			{"add a.mkv"},
+		}}
+		r := &Runner{
+			Thresholds: noLimits,
+			Settle:     Settle{Interval: time.Millisecond, Attempts: 3},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 2, result.SettleChecks)
+		assert.Equal(t, []string{"a.mkv"}, result.Result.Added)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("Unsettled changes defer sync", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffSeq: [][]string{
+			{"add a.part"},
+			{"add b.part"},
+			{"add c.part"},
+		}}
+		r := &Runner{
+			Thresholds: noLimits,
+			Steps:      Steps{Scrub: true},
+			Settle:     Settle{Interval: time.Millisecond, Attempts: 2},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error, "A deferred run is not a failure")
+		assert.Empty(t, result.ErrorKind)
+		assert.True(t, result.Deferred)
+		assert.Equal(t, 2, result.SettleChecks)
+		assert.Equal(t, []string{"c.part"}, result.Result.Added)
+		assert.Equal(t, 3, f.DiffCount)
+		assert.Equal(t, 0, f.SyncCount)
+		assert.Equal(t, 0, f.ScrubCount)
+	})
+
+	t.Run("No changes skip the check", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"3 equal"}}
+		r := &Runner{
+			Thresholds: noLimits,
+			Settle:     Settle{Interval: time.Hour, Attempts: 3},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 1, f.DiffCount)
+		assert.Zero(t, result.SettleChecks)
+	})
+
+	t.Run("Cancelled while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Thresholds: noLimits,
+			Settle:     Settle{Interval: time.Hour, Attempts: 3},
+			exec:       f,
+		}
+
+		result := r.Run(ctx)
+
+		assert.True(t, result.Cancelled())
+		assert.Equal(t, "diff", result.FailedStep)
+		assert.Equal(t, 1, f.DiffCount)
+		assert.Equal(t, 0, f.SyncCount)
+	})
+}
+
+func TestRunnerVerify(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+
+	t.Run("Clean verification diff", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffSeq: [][]string{{"add a.txt"}, {"1 equal"}}}
+		r := &Runner{
+			Steps:      Steps{Verify: true, Scrub: true},
+			Thresholds: noLimits,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Nil(t, result.ChangedDuringSync)
+		assert.Equal(t, 2, f.DiffCount)
+		assert.Equal(t, 1, f.ScrubCount)
+		assert.Equal(t, 1, result.AttemptsFor("verify"))
+	})
+
+	t.Run("Files changed during sync", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffSeq: [][]string{{"add a.txt"}, {"update a.txt", "add b.txt"}}}
+		r := &Runner{
+			Steps:      Steps{Verify: true, Scrub: true},
+			Thresholds: noLimits,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error, "Changed files are reported, not failed")
+		assert.Equal(t, []string{"a.txt"}, result.Result.Added)
+		assert.Equal(t, []string{"a.txt"}, result.ChangedDuringSync.Updated)
+		assert.Equal(t, []string{"b.txt"}, result.ChangedDuringSync.Added)
+		assert.Equal(t, 1, f.ScrubCount)
+	})
+
+	t.Run("Verification diff fails", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"add a.txt"}}
+		r := &Runner{
+			Steps:      Steps{Verify: true},
+			Thresholds: noLimits,
+			exec:       &failingVerify{fakeExec: f},
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "verify", result.FailedStep)
+		assert.Equal(t, 1, f.SyncCount)
+	})
+
+	t.Run("No sync, no verification", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{DiffLines: []string{"1 equal"}}
+		r := &Runner{
+			Steps:      Steps{Verify: true},
+			Thresholds: noLimits,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 1, f.DiffCount)
+	})
+}
+
+// failingVerify fails every Diff after the first.
+type failingVerify struct {
+	*fakeExec
+}
+
+func (f *failingVerify) Diff(ctx context.Context) (DiffResult, error) {
+	res, err := f.fakeExec.Diff(ctx)
+	if f.DiffCount > 1 {
+		return DiffResult{}, errors.New("snapraid diff failed")
+	}
+	return res, err
+}
+
+func TestRunnerRepair(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+	scrubErrors := &Summary{Exit: "error", DataErrors: 3}
+
+	t.Run("Fix and rescrub after scrub errors", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Summary:   scrubErrors,
+			ScrubErr:  errors.New("exit status 1"),
+			Fixed:     &Summary{Exit: "ok", DataErrors: 3, Recovered: 3},
+			Rescrub:   &Summary{Exit: "ok"},
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true, Smart: true},
+			Thresholds: noLimits,
+			Repair:     RepairFix,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error, "A confirmed repair does not fail the run")
+		assert.Equal(t, []string{"", "bad"}, f.Plans)
+		assert.Equal(t, 1, f.FixCount)
+		assert.Equal(t, 1, f.SmartCount, "Smart still runs after scrub found errors")
+		assert.Equal(t, "scrub", result.Repair.Trigger)
+		assert.Equal(t, 3, result.Repair.Errors)
+		assert.Equal(t, 3, result.Repair.Repaired)
+		assert.Len(t, result.Repair.Phases, 2)
+		assert.Equal(t, "fix", result.Repair.Phases[0].Name)
+		assert.Equal(t, "rescrub", result.Repair.Phases[1].Name)
+		assert.True(t, result.Repair.Confirmed())
+	})
+
+	t.Run("Unconfirmed repair keeps the scrub error", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Summary:   scrubErrors,
+			ScrubErr:  errors.New("exit status 1"),
+			Fixed:     &Summary{Exit: "error", DataErrors: 3, Recovered: 2, Unrecoverable: 1},
+			Rescrub:   &Summary{Exit: "error", DataErrors: 1},
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true},
+			Thresholds: noLimits,
+			Repair:     RepairFix,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "scrub", result.FailedStep)
+		assert.Equal(t, 2, result.Repair.Repaired)
+		assert.Equal(t, 1, result.Repair.Unrecoverable)
+		assert.False(t, result.Repair.Confirmed())
+	})
+
+	t.Run("Failing fix stops the repair", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     &StatusReport{SilentErrors: 2},
+			FixErr:    errors.New("snapraid fix failed"),
+		}
+		r := &Runner{
+			Steps:      Steps{Status: true},
+			Thresholds: noLimits,
+			Repair:     RepairFix,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "fix", result.FailedStep)
+		assert.Equal(t, "status", result.Repair.Trigger)
+		assert.Equal(t, 2, result.Repair.Errors)
+		assert.Equal(t, "snapraid fix failed", result.Repair.Phases[0].Error)
+		assert.Empty(t, f.Plans, "No rescrub after a failed fix")
+	})
+
+	t.Run("Check only", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     &StatusReport{SilentErrors: 2},
+			Fixed:     &Summary{Exit: "ok", Unrecoverable: 1},
+		}
+		r := &Runner{
+			Steps:      Steps{Status: true},
+			Thresholds: noLimits,
+			Repair:     RepairCheck,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 1, f.CheckCount)
+		assert.Equal(t, 0, f.FixCount)
+		assert.Equal(t, 1, result.Repair.Unrecoverable)
+		assert.False(t, result.Repair.Confirmed())
+	})
+
+	t.Run("Rescrub without summary does not confirm the repair", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Summary:   scrubErrors,
+			ScrubErr:  errors.New("exit status 1"),
+			Fixed:     &Summary{Exit: "ok", DataErrors: 3, Recovered: 3},
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true},
+			Thresholds: noLimits,
+			Repair:     RepairFix,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "scrub", result.FailedStep)
+		assert.Len(t, result.Repair.Phases, 2)
+		assert.False(t, result.Repair.Confirmed())
+	})
+
+	t.Run("Repair phases are skipped without budget", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Summary:   scrubErrors,
+			ScrubErr:  errors.New("exit status 1"),
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true},
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Repair: 2 * time.Hour, Total: time.Hour},
+			Repair:     RepairFix,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "scrub", result.FailedStep)
+		assert.Equal(t, 0, f.FixCount)
+		assert.Equal(t, []string{"fix"}, result.Skipped)
+		assert.Empty(t, result.Repair.Phases)
+		assert.False(t, result.Repair.Confirmed())
+	})
+
+	t.Run("Repair phases use the repair timeout and retry policy", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     &StatusReport{SilentErrors: 2},
+			FixErr:    &CommandError{Cmd: "check", Stderr: "Content file '/a' is locked", Err: errors.New("exit status 1")},
+		}
+		r := &Runner{
+			Steps:      Steps{Status: true},
+			Thresholds: noLimits,
+			Timeouts:   Timeouts{Repair: time.Hour},
+			Retries:    Retries{Repair: RetryPolicy{MaxAttempts: 2, StderrPatterns: []*regexp.Regexp{regexp.MustCompile("locked")}}},
+			Repair:     RepairCheck,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "check", result.FailedStep)
+		assert.Equal(t, 2, f.CheckCount)
+
+		var attempts []Attempt
+		for _, a := range result.Attempts {
+			if a.Step == "check" {
+				attempts = append(attempts, Attempt{Step: a.Step, Attempt: a.Attempt})
+			}
+		}
+		assert.Equal(t, []Attempt{{Step: "check", Attempt: 1}, {Step: "check", Attempt: 2}}, attempts)
+		assert.Equal(t, time.Hour, r.Timeouts.forStep("rescrub"))
+	})
+
+	t.Run("Repair off keeps scrub errors fatal", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Summary:   scrubErrors,
+			ScrubErr:  errors.New("exit status 1"),
+		}
+		r := &Runner{
+			Steps:      Steps{Scrub: true, Smart: true},
+			Thresholds: noLimits,
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "scrub", result.FailedStep)
+		assert.Nil(t, result.Repair)
+		assert.Equal(t, 0, f.SmartCount)
+		assert.Equal(t, 0, f.FixCount)
+	})
+}
+
+func TestRunnerScrubPlanner(t *testing.T) {
+	t.Parallel()
+
+	behind := &StatusReport{ScrubAge: &ScrubAge{Oldest: 60, Median: 30, Newest: 0}, NotScrubbed: 10}
+
+	t.Run("Scrubs with the planned percentage", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     behind,
+		}
+		r := &Runner{
+			Steps:   Steps{Scrub: true},
+			Planner: ScrubPlanner{TargetDays: 30},
+			exec:    f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []string{"57"}, f.Plans)
+		assert.Equal(t, []int{28}, f.OlderThan)
+		assert.Equal(t, 1, f.StatusCount, "Status is read to plan the scrub")
+		assert.Equal(t, 1, result.AttemptsFor("plan"))
+		assert.Equal(t, "57", result.ScrubPlan.Plan)
+	})
+
+	t.Run("Failing status keeps the configured plan", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			StatusErr: errors.New("status failed"),
+		}
+		r := &Runner{
+			Steps:   Steps{Scrub: true},
+			Planner: ScrubPlanner{TargetDays: 30},
+			exec:    f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error, "A failed plan does not fail the run")
+		assert.Equal(t, []string{""}, f.Plans)
+		assert.Empty(t, result.ScrubPlan.Plan)
+		assert.Contains(t, result.ScrubPlan.Reason, "status failed")
+	})
+
+	t.Run("Capped by the scrub timeout using past runs", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		past := RunResult{
+			Timestamp: time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
+			Timings:   RunTimings{Scrub: 100 * time.Minute},
+			ScrubPlan: &ScrubDecision{Plan: "10"},
+		}
+		assert.NoError(t, past.WriteJSON(dir))
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     behind,
+		}
+		r := &Runner{
+			Steps:     Steps{Scrub: true},
+			Planner:   ScrubPlanner{TargetDays: 30},
+			Timeouts:  Timeouts{Scrub: 30 * time.Minute},
+			outputDir: dir,
+			exec:      f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []string{"3"}, f.Plans)
+		assert.True(t, result.ScrubPlan.Capped)
+		assert.Equal(t, 30*time.Minute, result.ScrubPlan.Estimate)
+	})
+
+	t.Run("Skips scrub if less than one percent fits", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		past := RunResult{
+			Timestamp: time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
+			Timings:   RunTimings{Scrub: 100 * time.Minute},
+			ScrubPlan: &ScrubDecision{Plan: "10"},
+		}
+		assert.NoError(t, past.WriteJSON(dir))
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     behind,
+		}
+		r := &Runner{
+			Steps:     Steps{Scrub: true},
+			Planner:   ScrubPlanner{TargetDays: 30, MaxDuration: 5 * time.Minute},
+			outputDir: dir,
+			exec:      f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, 0, f.ScrubCount)
+		assert.Nil(t, result.Scrub)
+		assert.True(t, result.ScrubPlan.Skip)
+	})
+
+	t.Run("Disabled planner uses the configured plan", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"1 equal"},
+			Array:     behind,
+		}
+		r := &Runner{
+			Steps: Steps{Scrub: true},
+			exec:  f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []string{""}, f.Plans)
+		assert.Equal(t, 0, f.StatusCount)
+		assert.Nil(t, result.ScrubPlan)
+	})
+}
+
+func TestRunnerForce(t *testing.T) {
+	t.Parallel()
+
+	noLimits := Thresholds{Add: -1, Remove: -1, Update: -1, Move: -1, Copy: -1, Restore: -1}
+	zeroSize := func(files ...ZeroSizeFile) error {
+		cmdErr := &CommandError{Cmd: "sync", Stderr: "has unexpected zero size!", Err: errors.New("exit status 1")}
+		return &SyncRefusalError{SyncRefusal: SyncRefusal{ZeroSize: files}, Err: cmdErr}
+	}
+	lock := ZeroSizeFile{Disk: "d1", RelPath: "app/db.lock", AbsPath: "/mnt/d1/app/db.lock"}
+	movie := ZeroSizeFile{Disk: "d1", RelPath: "movies/a.mkv", AbsPath: "/mnt/d1/movies/a.mkv"}
+
+	t.Run("Allowlisted files are forced", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   zeroSize(lock),
+			SyncFails: 1,
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Force:      ForcePolicy{ZeroSize: []string{"*.lock"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, [][]string{nil, {ForceZero}}, f.Forces)
+		assert.Equal(t, []string{ForceZero}, result.ForcedSync)
+		assert.Equal(t, []ZeroSizeFile{lock}, result.SyncRefusal.ZeroSize)
+		assert.Equal(t, 2, result.AttemptsFor("sync"))
+	})
+
+	t.Run("Files not allowlisted fail the run", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   zeroSize(lock, movie),
+			SyncFails: 1,
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Force:      ForcePolicy{ZeroSize: []string{"*.lock"}},
+			Retries:    Retries{Sync: RetryPolicy{MaxAttempts: 3, StderrPatterns: []*regexp.Regexp{regexp.MustCompile("zero size")}}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, ErrorKindZeroSize, result.ErrorKind)
+		assert.Equal(t, "sync", result.FailedStep)
+		assert.Equal(t, 1, f.SyncCount, "Refusals are neither retried nor forced")
+		assert.Empty(t, result.ForcedSync)
+		assert.Equal(t, []ZeroSizeFile{lock, movie}, result.SyncRefusal.ZeroSize)
+	})
+
+	t.Run("Refused again after forcing", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncErr:   zeroSize(lock),
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Force:      ForcePolicy{ZeroSize: []string{"*.lock"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Equal(t, ErrorKindZeroSize, result.ErrorKind)
+		assert.Equal(t, 2, f.SyncCount, "Each force option is tried once")
+		assert.Equal(t, []string{ForceZero}, result.ForcedSync)
+	})
+
+	t.Run("Empty disk is forced after the refused zero-size files", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{"add a.txt"},
+			SyncSeq: []error{
+				zeroSize(lock),
+				&SyncRefusalError{SyncRefusal: SyncRefusal{EmptyDisks: []DiffWarning{{Disk: "d3", Dir: "/mnt/d3/"}}}},
+			},
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Force:      ForcePolicy{ZeroSize: []string{"*.lock"}, EmptyDisks: []string{"d3"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Equal(t, [][]string{nil, {ForceZero}, {ForceZero, ForceEmpty}}, f.Forces)
+		assert.Equal(t, []string{ForceZero, ForceEmpty}, result.ForcedSync)
+		assert.Len(t, result.SyncRefusal.ZeroSize, 1)
+		assert.Len(t, result.SyncRefusal.EmptyDisks, 1)
+	})
+
+	t.Run("Allowlisted empty disk passes the gate and is forced", func(t *testing.T) {
+		t.Parallel()
+
+		emptied := DiffWarning{Kind: WarningMissingDisk, Disk: "d3", Dir: "/mnt/d3/"}
+		f := &fakeExec{
+			DiffLines: []string{
+				"remove /mnt/d3/a.txt",
+				"remove /mnt/d3/b.txt",
+				"add /mnt/d1/new.txt",
+				"All the files previously present in disk 'd3' at dir '/mnt/d3/'",
+				"     10 equal",
+			},
+			Disks:     []DataDisk{{Name: "d1", Dir: "/mnt/d1/"}, {Name: "d3", Dir: "/mnt/d3/"}},
+			SyncErr:   &SyncRefusalError{SyncRefusal: SyncRefusal{EmptyDisks: []DiffWarning{emptied}}},
+			SyncFails: 1,
+		}
+		limits := noLimits
+		limits.Remove = 0
+		r := &Runner{
+			Thresholds: limits,
+			Force:      ForcePolicy{EmptyDisks: []string{"d3"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.NoError(t, result.Error)
+		assert.Empty(t, result.Violations)
+		assert.Empty(t, result.Fingerprint)
+		assert.Equal(t, [][]string{nil, {ForceEmpty}}, f.Forces)
+		assert.Equal(t, []string{ForceEmpty}, result.ForcedSync)
+		assert.Equal(t, 2, result.Result.Count("removed"), "Removed files are still reported")
+		assert.Len(t, result.Result.BlockingWarnings(), 1, "The warning is still reported")
+	})
+
+	t.Run("Empty disk not allowlisted blocks at the gate", func(t *testing.T) {
+		t.Parallel()
+
+		f := &fakeExec{
+			DiffLines: []string{
+				"remove /mnt/d3/a.txt",
+				"All the files previously present in disk 'd3' at dir '/mnt/d3/'",
+			},
+			Disks: []DataDisk{{Name: "d3", Dir: "/mnt/d3/"}},
+		}
+		r := &Runner{
+			Thresholds: noLimits,
+			Force:      ForcePolicy{EmptyDisks: []string{"d2"}},
+			exec:       f,
+		}
+
+		result := r.Run(context.Background())
+
+		assert.Error(t, result.Error)
+		assert.Equal(t, "thresholds", result.FailedStep)
+		assert.Equal(t, 0, f.SyncCount)
+		assert.Equal(t, ThresholdViolations{{Category: "missing_disk", Disk: "d3", Dir: "/mnt/d3/"}}, result.Violations)
+	})
+}
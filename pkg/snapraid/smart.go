@@ -0,0 +1,125 @@
+package snapraid
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// arrayFailurePattern matches the array-wide estimate printed below the smart table, e.g.
+// "Probability that at least one disk is going to fail in the next year is 64%."
+var arrayFailurePattern = regexp.MustCompile(`(?i)probability that at least one disk .* is (\d+)%`)
+
+// SmartDisk is a single row of the `snapraid smart` report.
+// Values snapraid cannot determine (printed as "-") are nil.
+type SmartDisk struct {
+	Disk               string   `json:"disk"`                          // snapraid disk name, "-" if the device is not part of the array
+	Device             string   `json:"device"`                        // device path, e.g. "/dev/sdb"
+	Serial             string   `json:"serial,omitempty"`              // disk serial number
+	Temperature        *int     `json:"temperature,omitempty"`         // current temperature in °C
+	PowerOnDays        *int     `json:"power_on_days,omitempty"`       // power-on time in days
+	ErrorCount         *int     `json:"error_count,omitempty"`         // number of errors in the SMART error log
+	ErrorStatus        string   `json:"error_status,omitempty"`        // non-numeric error column, e.g. "logfail"
+	FailureProbability *int     `json:"failure_probability,omitempty"` // estimated failure probability in the next year, in percent
+	SizeTB             *float64 `json:"size_tb,omitempty"`             // disk size in TB
+}
+
+// SmartReport is the parsed output of `snapraid smart`.
+type SmartReport struct {
+	Disks              []SmartDisk `json:"disks"`                         // one entry per device
+	FailureProbability *int        `json:"failure_probability,omitempty"` // probability that at least one disk fails in the next year, in percent
+}
+
+// SmartLimits define when a disk is considered unhealthy. Set a limit to -1 to disable it.
+type SmartLimits struct {
+	FailureProbability int // FailureProbability is the maximum failure probability in percent.
+	Temperature        int // Temperature is the maximum temperature in °C.
+}
+
+// ExceedsLimits returns true if the disk is over any enabled limit or reports SMART log errors.
+func (d SmartDisk) ExceedsLimits(l SmartLimits) bool {
+	if l.FailureProbability >= 0 && d.FailureProbability != nil && *d.FailureProbability > l.FailureProbability {
+		return true
+	}
+	if l.Temperature >= 0 && d.Temperature != nil && *d.Temperature > l.Temperature {
+		return true
+	}
+	return d.ErrorStatus != "" || (d.ErrorCount != nil && *d.ErrorCount > 0)
+}
+
+// parseSmart parses the output of `snapraid smart`. The disk table starts after the
+// dashed separator line and ends at the first empty line. It returns nil if no table was found.
+func parseSmart(lines []string) *SmartReport {
+	var rep SmartReport
+	inTable := false
+	found := false
+
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+
+		if m := arrayFailurePattern.FindStringSubmatch(line); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				rep.FailureProbability = &n
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "---"):
+			inTable, found = true, true
+			continue
+		case line == "":
+			inTable = false
+			continue
+		case !inTable:
+			continue
+		}
+
+		if disk, ok := parseSmartRow(line); ok {
+			rep.Disks = append(rep.Disks, disk)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &rep
+}
+
+// parseSmartRow parses a table row such as
+// "31  856  0  5%  4.0  WD-WCC4E1234567  /dev/sdb  d1".
+func parseSmartRow(line string) (SmartDisk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 8 {
+		return SmartDisk{}, false
+	}
+
+	n := len(fields)
+	disk := SmartDisk{
+		Temperature:        smartInt(fields[0]),
+		PowerOnDays:        smartInt(fields[1]),
+		FailureProbability: smartInt(strings.TrimSuffix(fields[3], "%")),
+		Serial:             strings.Join(fields[5:n-2], " "),
+		Device:             fields[n-2],
+		Disk:               fields[n-1],
+	}
+	if disk.ErrorCount = smartInt(fields[2]); disk.ErrorCount == nil && fields[2] != "-" {
+		disk.ErrorStatus = fields[2]
+	}
+	if size, err := strconv.ParseFloat(fields[4], 64); err == nil {
+		disk.SizeTB = &size
+	}
+	if disk.Serial == "-" {
+		disk.Serial = ""
+	}
+	return disk, true
+}
+
+// smartInt parses a numeric column. "-" and other non-numeric values yield nil.
+func smartInt(s string) *int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,104 @@
+package snapraid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gi8lino/go-snapraid/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const smartOutput = `SnapRAID SMART report:
+
+   Temp  Power   Error   FP Size
+      C OnDays   Count        TB  Serial           Device    Disk
+ -----------------------------------------------------------------------
+     31    856       0   5%  4.0  WD-WCC4E1234567  /dev/sdb  d1
+     47   1125       3  62%  4.0  WD-WCC4E7654321  /dev/sdc  d2
+      -      -       -  SSD  0.5  -                /dev/sda  -
+     34    860 logfail   -   8.0  ZA1B2C3D         /dev/sdd  parity
+
+The FP column is the estimated probability (in percentage) that the disk
+is going to fail in the next year.
+
+Probability that at least one disk is going to fail in the next year is 64%.
+`
+
+func TestParseSmart(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Full report", func(t *testing.T) {
+		t.Parallel()
+
+		rep := parseSmart(strings.Split(smartOutput, "\n"))
+		assert.NotNil(t, rep)
+		assert.Equal(t, utils.Ptr(64), rep.FailureProbability)
+		assert.Len(t, rep.Disks, 4)
+
+		assert.Equal(t, SmartDisk{
+			Disk:               "d1",
+			Device:             "/dev/sdb",
+			Serial:             "WD-WCC4E1234567",
+			Temperature:        utils.Ptr(31),
+			PowerOnDays:        utils.Ptr(856),
+			ErrorCount:         utils.Ptr(0),
+			FailureProbability: utils.Ptr(5),
+			SizeTB:             utils.Ptr(4.0),
+		}, rep.Disks[0])
+
+		ssd := rep.Disks[2]
+		assert.Equal(t, "-", ssd.Disk)
+		assert.Empty(t, ssd.Serial)
+		assert.Nil(t, ssd.Temperature)
+		assert.Nil(t, ssd.FailureProbability)
+		assert.Empty(t, ssd.ErrorStatus)
+
+		parity := rep.Disks[3]
+		assert.Equal(t, "parity", parity.Disk)
+		assert.Nil(t, parity.ErrorCount)
+		assert.Equal(t, "logfail", parity.ErrorStatus)
+	})
+
+	t.Run("No table", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, parseSmart([]string{"Running smart", "No disks found"}))
+	})
+}
+
+func TestSmartDiskExceedsLimits(t *testing.T) {
+	t.Parallel()
+
+	limits := SmartLimits{FailureProbability: 50, Temperature: 45}
+
+	t.Run("Healthy disk", func(t *testing.T) {
+		t.Parallel()
+		d := SmartDisk{Temperature: utils.Ptr(31), FailureProbability: utils.Ptr(5), ErrorCount: utils.Ptr(0)}
+		assert.False(t, d.ExceedsLimits(limits))
+	})
+
+	t.Run("Too hot", func(t *testing.T) {
+		t.Parallel()
+		d := SmartDisk{Temperature: utils.Ptr(46)}
+		assert.True(t, d.ExceedsLimits(limits))
+	})
+
+	t.Run("Failure probability too high", func(t *testing.T) {
+		t.Parallel()
+		d := SmartDisk{FailureProbability: utils.Ptr(62)}
+		assert.True(t, d.ExceedsLimits(limits))
+	})
+
+	t.Run("Disabled limits", func(t *testing.T) {
+		t.Parallel()
+		d := SmartDisk{Temperature: utils.Ptr(60), FailureProbability: utils.Ptr(90)}
+		assert.False(t, d.ExceedsLimits(SmartLimits{FailureProbability: -1, Temperature: -1}))
+	})
+
+	t.Run("SMART log errors", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, SmartDisk{ErrorCount: utils.Ptr(3)}.ExceedsLimits(limits))
+		assert.True(t, SmartDisk{ErrorStatus: "logfail"}.ExceedsLimits(limits))
+	})
+}
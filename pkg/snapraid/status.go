@@ -0,0 +1,153 @@
+package snapraid
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// scrubAgePattern matches "The oldest block was scrubbed 10 days ago, the median 5, the newest 0."
+	scrubAgePattern = regexp.MustCompile(`(?i)oldest block was scrubbed (\d+) days? ago, the median (\d+), the newest (\d+)`)
+	// syncProgressPattern matches "You have a sync in progress at 53%."
+	syncProgressPattern = regexp.MustCompile(`(?i)sync in progress at (\d+)%`)
+	// notScrubbedPattern matches "The 2% of the array is not scrubbed."
+	notScrubbedPattern = regexp.MustCompile(`(?i)(\d+)% of the array is not scrubbed`)
+	// silentErrorsPattern matches "DANGER! In the array there are 5 errors!"
+	silentErrorsPattern = regexp.MustCompile(`(?i)in the array there are (\d+) errors`)
+)
+
+// StatusDisk is a single row of the `snapraid status` disk table.
+// Values snapraid prints as "-" are nil.
+type StatusDisk struct {
+	Name            string   `json:"name"`                  // snapraid disk name; empty for the totals row
+	Files           int      `json:"files"`                 // number of files
+	FragmentedFiles int      `json:"fragmented_files"`      // number of fragmented files
+	ExcessFragments int      `json:"excess_fragments"`      // number of fragments beyond one per file
+	WastedGB        *float64 `json:"wasted_gb,omitempty"`   // space wasted by parity alignment in GB
+	UsedGB          *float64 `json:"used_gb,omitempty"`     // used space in GB
+	FreeGB          *float64 `json:"free_gb,omitempty"`     // free space in GB
+	UsePercent      *int     `json:"use_percent,omitempty"` // used space in percent
+}
+
+// ScrubAge summarizes the scrub-age histogram in days.
+type ScrubAge struct {
+	Oldest int `json:"oldest"` // days since the least recently scrubbed block was checked
+	Median int `json:"median"` // median age in days
+	Newest int `json:"newest"` // days since the most recently scrubbed block was checked
+}
+
+// StatusReport is the parsed output of `snapraid status`.
+type StatusReport struct {
+	Disks          []StatusDisk `json:"disks"`                   // one entry per data disk
+	Total          *StatusDisk  `json:"total,omitempty"`         // totals row below the disk table
+	ScrubAge       *ScrubAge    `json:"scrub_age,omitempty"`     // scrub-age histogram summary
+	NotScrubbed    int          `json:"not_scrubbed_percent"`    // share of the array that was never scrubbed, in percent
+	SyncInProgress bool         `json:"sync_in_progress"`        // true if an interrupted sync has to be completed
+	SyncProgress   int          `json:"sync_progress,omitempty"` // completion of the interrupted sync in percent
+	SilentErrors   int          `json:"silent_errors"`           // number of blocks with silent errors
+	Warnings       []string     `json:"warnings,omitempty"`      // "WARNING!" and "DANGER!" lines as printed by snapraid
+}
+
+// Healthy returns true if no sync is pending and no silent errors were found.
+func (s StatusReport) Healthy() bool {
+	return !s.SyncInProgress && s.SilentErrors == 0
+}
+
+// parseStatus parses the output of `snapraid status`. Disk rows are read between the
+// table header and the dashed separator; the row after the separator holds the totals.
+// It returns nil if no disk table was found.
+func parseStatus(lines []string) *StatusReport {
+	var rep StatusReport
+	inTable, afterTable, found := false, false, false
+
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case strings.HasPrefix(line, "Files") && strings.Contains(line, "Name"):
+			inTable, found = true, true
+			continue
+		case strings.HasPrefix(line, "---"):
+			inTable, afterTable = false, true
+			continue
+		case line == "":
+			afterTable = false
+			continue
+		}
+
+		if inTable || afterTable {
+			if row, ok := parseStatusRow(line); ok {
+				if inTable {
+					rep.Disks = append(rep.Disks, row)
+				} else {
+					rep.Total = &row
+					afterTable = false
+				}
+			}
+			continue
+		}
+
+		if m := scrubAgePattern.FindStringSubmatch(line); m != nil {
+			rep.ScrubAge = &ScrubAge{Oldest: atoiOrZero(m[1]), Median: atoiOrZero(m[2]), Newest: atoiOrZero(m[3])}
+		}
+		if m := syncProgressPattern.FindStringSubmatch(line); m != nil {
+			rep.SyncInProgress = true
+			rep.SyncProgress = atoiOrZero(m[1])
+		}
+		if m := notScrubbedPattern.FindStringSubmatch(line); m != nil {
+			rep.NotScrubbed = atoiOrZero(m[1])
+		}
+		if m := silentErrorsPattern.FindStringSubmatch(line); m != nil {
+			rep.SilentErrors = atoiOrZero(m[1])
+		}
+		if strings.HasPrefix(line, "WARNING!") || strings.HasPrefix(line, "DANGER!") {
+			rep.Warnings = append(rep.Warnings, line)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &rep
+}
+
+// parseStatusRow parses a table row such as "29974  362  1675  1.5  2731  252  91%  d1".
+// The totals row has no name.
+func parseStatusRow(line string) (StatusDisk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return StatusDisk{}, false
+	}
+
+	var row StatusDisk
+	var err error
+	if row.Files, err = strconv.Atoi(fields[0]); err != nil {
+		return StatusDisk{}, false // header continuation line
+	}
+	row.FragmentedFiles = atoiOrZero(fields[1])
+	row.ExcessFragments = atoiOrZero(fields[2])
+	row.WastedGB = statusFloat(fields[3])
+	row.UsedGB = statusFloat(fields[4])
+	row.FreeGB = statusFloat(fields[5])
+	row.UsePercent = smartInt(strings.TrimSuffix(fields[6], "%"))
+	if len(fields) > 7 {
+		row.Name = strings.Join(fields[7:], " ")
+	}
+	return row, true
+}
+
+// statusFloat parses a numeric column. "-" and other non-numeric values yield nil.
+func statusFloat(s string) *float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// atoiOrZero parses s and returns 0 if it is not a number.
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
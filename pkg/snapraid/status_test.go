@@ -0,0 +1,100 @@
+package snapraid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gi8lino/go-snapraid/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const statusOutput = `SnapRAID status report:
+
+   Files Fragmented Excess  Wasted  Used    Free  Use Name
+            Files  Fragments  GB      GB      GB
+   29974     362     1675     1.5    2731     252  91% d1
+   33020     395     1890       -    2730     255  91% d2
+ --------------------------------------------------------------------------
+   63094     757     3565     3.4    5461     507  91%
+
+
+ 19%|o
+    |o                                                                  *
+  0%|o_________________________________________________________________*
+    10                    days ago of the last scrub/sync               0
+
+The oldest block was scrubbed 10 days ago, the median 5, the newest 0.
+
+WARNING! The array is NOT fully synced.
+You have a sync in progress at 53%.
+The 2% of the array is not scrubbed.
+No rehash is in progress or needed.
+DANGER! In the array there are 5 errors!
+`
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Full report", func(t *testing.T) {
+		t.Parallel()
+
+		rep := parseStatus(strings.Split(statusOutput, "\n"))
+		assert.NotNil(t, rep)
+
+		assert.Len(t, rep.Disks, 2)
+		assert.Equal(t, StatusDisk{
+			Name:            "d1",
+			Files:           29974,
+			FragmentedFiles: 362,
+			ExcessFragments: 1675,
+			WastedGB:        utils.Ptr(1.5),
+			UsedGB:          utils.Ptr(2731.0),
+			FreeGB:          utils.Ptr(252.0),
+			UsePercent:      utils.Ptr(91),
+		}, rep.Disks[0])
+		assert.Nil(t, rep.Disks[1].WastedGB)
+
+		assert.NotNil(t, rep.Total)
+		assert.Empty(t, rep.Total.Name)
+		assert.Equal(t, 63094, rep.Total.Files)
+
+		assert.Equal(t, &ScrubAge{Oldest: 10, Median: 5, Newest: 0}, rep.ScrubAge)
+		assert.True(t, rep.SyncInProgress)
+		assert.Equal(t, 53, rep.SyncProgress)
+		assert.Equal(t, 2, rep.NotScrubbed)
+		assert.Equal(t, 5, rep.SilentErrors)
+		assert.Equal(t, []string{
+			"WARNING! The array is NOT fully synced.",
+			"DANGER! In the array there are 5 errors!",
+		}, rep.Warnings)
+		assert.False(t, rep.Healthy())
+	})
+
+	t.Run("Healthy array", func(t *testing.T) {
+		t.Parallel()
+
+		out := strings.Join([]string{
+			"   Files Fragmented Excess  Wasted  Used    Free  Use Name",
+			"   100     0     0     0.0    10     5  66% d1",
+			" ------------------------------------------",
+			"   100     0     0     0.0    10     5  66%",
+			"",
+			"No sync is in progress.",
+			"No error detected.",
+		}, "\n")
+
+		rep := parseStatus(strings.Split(out, "\n"))
+		assert.NotNil(t, rep)
+		assert.False(t, rep.SyncInProgress)
+		assert.Zero(t, rep.SilentErrors)
+		assert.Empty(t, rep.Warnings)
+		assert.True(t, rep.Healthy())
+	})
+
+	t.Run("No table", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, parseStatus([]string{"Running status", "Loading state..."}))
+	})
+}
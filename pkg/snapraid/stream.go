@@ -0,0 +1,270 @@
+package snapraid
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// diffBuilder accumulates diff entries one at a time, so output can be parsed while it is
+// produced. It keeps at most limit paths per change category in memory and only counts the
+// rest. Every entry can additionally be spilled to a file, which then holds the complete list.
+type diffBuilder struct {
+	res   DiffResult
+	limit int           // maximum number of stored paths per category; 0 stores all
+	spill *os.File      // receives every entry as "<category>\t<path>" if set
+	w     *bufio.Writer // buffers writes to spill
+	err   error         // first error writing to spill
+}
+
+// newDiffBuilder returns a builder storing at most limit paths per category. If spillDir is
+// set, every entry is also written to a new file in that directory.
+func newDiffBuilder(limit int, spillDir string) (*diffBuilder, error) {
+	b := &diffBuilder{limit: limit}
+	if spillDir == "" {
+		return b, nil
+	}
+
+	f, err := os.CreateTemp(spillDir, "go-snapraid-diff-*.tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diff spill file: %w", err)
+	}
+	b.spill, b.w = f, bufio.NewWriter(f)
+	return b, nil
+}
+
+// add records an added, removed, updated or restored file.
+func (b *diffBuilder) add(category, disk, path string) {
+	b.write(category, path)
+	if !b.keep(category) {
+		return
+	}
+	list := b.res.list(category)
+	*list = append(*list, path)
+	b.res.Entries = append(b.res.Entries, newEntry(category, disk, path))
+}
+
+// transfer records a moved or copied file.
+func (b *diffBuilder) transfer(category string, t Transfer) {
+	path := t.To
+	if t.From != "" {
+		path = t.From + " -> " + t.To
+	}
+	b.write(category, path)
+	if !b.keep(category) {
+		return
+	}
+	list := b.res.list(category)
+	*list = append(*list, path)
+	b.res.Entries = append(b.res.Entries, newEntry(category, t.ToDisk, t.To))
+	if category == "moved" {
+		b.res.Moves = append(b.res.Moves, t)
+	} else {
+		b.res.Copies = append(b.res.Copies, t)
+	}
+}
+
+// keep reports whether another path of category fits into memory. If not, the entry is
+// only counted as omitted.
+func (b *diffBuilder) keep(category string) bool {
+	if b.limit <= 0 || len(*b.res.list(category)) < b.limit {
+		return true
+	}
+	if b.res.Omitted == nil {
+		b.res.Omitted = make(map[string]int, len(changeCategories))
+	}
+	b.res.Omitted[category]++
+	return false
+}
+
+// write appends an entry to the spill file, if any.
+func (b *diffBuilder) write(category, path string) {
+	if b.w == nil || b.err != nil {
+		return
+	}
+	_, b.err = fmt.Fprintf(b.w, "%s\t%s\n", category, path)
+}
+
+// line parses a single line of the human-readable `snapraid diff` output. It recognizes:
+//   - "<number> equal" (possibly indented), to accumulate into Equal.
+//   - "<number> added", "<number> removed", etc., stored in Reported.
+//   - "add <path>", "remove <path>", etc., splitting on the first space so that
+//     <path> may contain escaped spaces or parentheses, which are unescaped.
+//   - "move <old> -> <new>" and "copy <src> -> <dst>", stored as "old -> new".
+//   - warnings about missing or empty disks and other "WARNING!" lines, see parseDiffWarning.
+//
+// It returns true if the line described a changed file.
+func (b *diffBuilder) line(raw string) bool {
+	// Trim leading/trailing whitespace
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		return false
+	}
+
+	if w, ok := parseDiffWarning(line); ok {
+		b.res.Warnings = append(b.res.Warnings, w)
+		return false
+	}
+
+	// Check for "<number> equal" and "<number> added" etc. summaries (two fields exactly)
+	parts := strings.Fields(line)
+	if len(parts) == 2 {
+		if count, err := strconv.Atoi(parts[0]); err == nil {
+			counter := strings.ToLower(parts[1])
+			if counter == "equal" {
+				b.res.Equal += count
+				return false
+			}
+			if slices.Contains(changeCategories, counter) {
+				b.res.report(counter, count)
+				return false
+			}
+		}
+	}
+
+	// Otherwise, split at the first space to separate action from path
+	// e.g. "add /XOXO\ \(2016\)/... or
+	//      "remove filme/Zoolander\ \(2001\)/..."
+	idx := strings.IndexRune(line, ' ')
+	if idx < 0 {
+		// no space ⇒ not an action/path line
+		return false
+	}
+
+	action := strings.ToLower(line[:idx])   // "add", "remove", etc.
+	rest := strings.TrimSpace(line[idx+1:]) // the rest of the line, including any escaped spaces
+
+	switch action {
+	case "add":
+		b.add("added", "", unescapeShell(rest))
+	case "remove":
+		b.add("removed", "", unescapeShell(rest))
+	case "update":
+		b.add("updated", "", unescapeShell(rest))
+	case "restore":
+		b.add("restored", "", unescapeShell(rest))
+	case "move":
+		b.transfer("moved", parseTransfer(rest))
+	case "copy":
+		b.transfer("copied", parseTransfer(rest))
+	default:
+		// unrecognized action ⇒ ignore
+		return false
+	}
+	return true
+}
+
+// isChangeLine reports whether a trimmed line of the human-readable `snapraid diff` output
+// describes a changed file, see line.
+func isChangeLine(line string) bool {
+	action, _, ok := strings.Cut(line, " ")
+	return ok && slices.Contains([]string{"add", "remove", "update", "restore", "move", "copy"}, strings.ToLower(action))
+}
+
+// readTextDiff parses the human-readable `snapraid diff` output in f from its start into b.
+func readTextDiff(f *os.File, b *diffBuilder) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			b.line(strings.TrimRight(line, "\r\n"))
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseTransfer splits "<old> -> <new>" into a Transfer. Escaped paths never contain a
+// bare " -> ", so it separates source and destination. Without it, only To is set.
+func parseTransfer(s string) Transfer {
+	from, to, ok := strings.Cut(s, " -> ")
+	if !ok {
+		return Transfer{To: unescapeShell(s)}
+	}
+	return Transfer{From: unescapeShell(from), To: unescapeShell(to)}
+}
+
+// result finishes the spill file and returns the accumulated diff.
+func (b *diffBuilder) result() (DiffResult, error) {
+	if b.spill == nil {
+		return b.res, nil
+	}
+
+	err := b.err
+	if err == nil {
+		err = b.w.Flush()
+	}
+	if cerr := b.spill.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return b.res, fmt.Errorf("failed to write diff spill file: %w", err)
+	}
+	b.res.SpillFile = b.spill.Name()
+	return b.res, nil
+}
+
+// discard removes the spill file of a builder whose result is not used.
+func (b *diffBuilder) discard() {
+	if b.spill == nil {
+		return
+	}
+	b.spill.Close()           // nolint:errcheck
+	os.Remove(b.spill.Name()) // nolint:errcheck
+}
+
+// lineWriter is an io.Writer that calls fn for every complete line written to it.
+type lineWriter struct {
+	fn  func(line string)
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+// newLineWriter returns a lineWriter calling fn for every line.
+func newLineWriter(fn func(line string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+// Write implements io.Writer. Partial lines are kept until the next newline.
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	total := len(p)
+	for {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			lw.buf.Write(p)
+			return total, nil
+		}
+		lw.buf.Write(p[:idx])
+		lw.fn(strings.TrimRight(lw.buf.String(), "\r"))
+		lw.buf.Reset()
+		p = p[idx+1:]
+	}
+}
+
+// Flush passes a trailing line without newline to fn.
+func (lw *lineWriter) Flush() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.buf.Len() > 0 {
+		lw.fn(lw.buf.String())
+		lw.buf.Reset()
+	}
+}
@@ -0,0 +1,139 @@
+package snapraid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffBuilder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Keeps every path without limit", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := newDiffBuilder(0, "")
+		assert.NoError(t, err)
+		for _, p := range []string{"a", "b", "c"} {
+			b.add("added", "d1", p)
+		}
+
+		res, err := b.result()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, res.Added)
+		assert.Equal(t, 3, res.Count("added"))
+		assert.False(t, res.Truncated())
+		assert.Empty(t, res.SpillFile)
+	})
+
+	t.Run("Counts paths over the limit", func(t *testing.T) {
+		t.Parallel()
+
+		b, err := newDiffBuilder(2, "")
+		assert.NoError(t, err)
+		for _, p := range []string{"a", "b", "c", "d"} {
+			b.add("added", "d1", p)
+		}
+		b.transfer("moved", Transfer{From: "x", To: "y", FromDisk: "d1", ToDisk: "d1"})
+		b.res.report("added", 4)
+
+		res, err := b.result()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, res.Added)
+		assert.Len(t, res.Entries, 3)
+		assert.Equal(t, map[string]int{"added": 2}, res.Omitted)
+		assert.Equal(t, 4, res.Count("added"))
+		assert.Equal(t, 5, res.Changes())
+		assert.True(t, res.Truncated())
+		assert.NoError(t, res.verifyCounts())
+	})
+
+	t.Run("Spills every path to disk", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		b, err := newDiffBuilder(1, dir)
+		assert.NoError(t, err)
+		b.add("added", "d1", "a")
+		b.add("added", "d1", "b")
+		b.transfer("copied", Transfer{From: "src", To: "dst"})
+
+		res, err := b.result()
+		assert.NoError(t, err)
+		assert.Equal(t, dir, filepath.Dir(res.SpillFile))
+
+		raw, err := os.ReadFile(res.SpillFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "added\ta\nadded\tb\ncopied\tsrc -> dst\n", string(raw))
+	})
+
+	t.Run("Discard removes the spill file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		b, err := newDiffBuilder(0, dir)
+		assert.NoError(t, err)
+		b.add("added", "d1", "a")
+		b.discard()
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Missing spill dir", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newDiffBuilder(0, filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create diff spill file")
+	})
+
+	t.Run("Line reports per-file lines", func(t *testing.T) {
+		t.Parallel()
+
+		b := &diffBuilder{}
+		assert.True(t, b.line(`add movies/a\ b.mkv`))
+		assert.True(t, b.line("move old.txt -> new.txt"))
+		assert.False(t, b.line("  3 equal"))
+		assert.False(t, b.line("2 added"))
+		assert.False(t, b.line("Comparing..."))
+		assert.False(t, b.line(""))
+
+		assert.Equal(t, []string{"movies/a b.mkv"}, b.res.Added)
+		assert.Equal(t, 3, b.res.Equal)
+		assert.Equal(t, map[string]int{"added": 2}, b.res.Reported)
+	})
+}
+
+func TestLineWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Splits writes into lines", func(t *testing.T) {
+		t.Parallel()
+
+		var lines []string
+		lw := newLineWriter(func(line string) { lines = append(lines, line) })
+
+		n, err := lw.Write([]byte("first\r\nsec"))
+		assert.NoError(t, err)
+		assert.Equal(t, 10, n)
+		_, _ = lw.Write([]byte("ond\nthird"))
+		assert.Equal(t, []string{"first", "second"}, lines)
+
+		lw.Flush()
+		assert.Equal(t, []string{"first", "second", "third"}, lines)
+	})
+
+	t.Run("Flush without partial line", func(t *testing.T) {
+		t.Parallel()
+
+		var lines []string
+		lw := newLineWriter(func(line string) { lines = append(lines, line) })
+		_, _ = lw.Write([]byte("xxx\n"))
+		lw.Flush()
+		assert.Equal(t, []string{"xxx"}, lines)
+	})
+}
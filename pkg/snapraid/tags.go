@@ -0,0 +1,172 @@
+package snapraid
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Summary holds the counters snapraid reports in "summary:" tags after sync or scrub.
+type Summary struct {
+	Exit       string `json:"exit,omitempty"` // exit status reported by snapraid ("ok", "error", "equal", "diff")
+	FileErrors int    `json:"file_errors"`    // errors accessing files
+	IOErrors   int    `json:"io_errors"`      // input/output errors on disks
+	DataErrors int    `json:"data_errors"`    // silent data errors (checksum mismatches)
+
+	Recovered     int `json:"recovered_errors,omitempty"`     // errors repaired by fix
+	Unrecoverable int `json:"unrecoverable_errors,omitempty"` // errors check or fix cannot repair
+}
+
+// HasErrors returns true if any error counter is non-zero.
+func (s Summary) HasErrors() bool {
+	return s.FileErrors > 0 || s.IOErrors > 0 || s.DataErrors > 0
+}
+
+// tagReport is everything extracted from snapraid's tagged log output.
+type tagReport struct {
+	Diff    DiffResult // per-file diff entries and the "equal" counter
+	Summary Summary    // exit status and error counters
+}
+
+// parseTags reads snapraid's machine-readable log (as written by --log) and builds a tagReport.
+// Lines look like "scan:add:d1:movies/file.mkv" or "summary:error_io:0". Diff entries are
+// collected by b, so the log is never held in memory as a whole; if b is nil, only the
+// summary is read and scan tags are skipped. The second return value is false if no
+// recognized tag was found, e.g. because an old snapraid wrote nothing. An error reading
+// the log is returned together with the tags read so far.
+func parseTags(r io.Reader, b *diffBuilder) (tagReport, bool, error) {
+	var rep tagReport
+	found := false
+
+	diff := &rep.Diff
+	if b != nil {
+		diff = &b.res
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), ":")
+		if len(fields) < 3 {
+			continue
+		}
+
+		switch fields[0] {
+		case "scan", "diff":
+			if b != nil && parseScanTag(b, fields[1], fields[2:]) {
+				found = true
+			}
+		case "summary":
+			if parseSummaryTag(diff, &rep.Summary, fields[1], fields[2]) {
+				found = true
+			}
+		}
+	}
+	rep.Diff = *diff
+	if err := scanner.Err(); err != nil {
+		return rep, found, fmt.Errorf("failed to read snapraid log: %w", err)
+	}
+	return rep, found, nil
+}
+
+// parseScanTag adds a single "scan:<action>:..." entry to b.
+// add/remove/update/restore carry "<disk>:<path>", move carries "<disk>:<old>:<new>"
+// and copy carries "<src disk>:<src path>:<disk>:<path>".
+func parseScanTag(b *diffBuilder, action string, args []string) bool {
+	switch action {
+	case "add", "remove", "update", "restore":
+		if len(args) < 2 {
+			return false
+		}
+		category := map[string]string{"add": "added", "remove": "removed", "update": "updated", "restore": "restored"}[action]
+		b.add(category, args[0], unescapeTag(args[1]))
+	case "move":
+		if len(args) < 3 {
+			return false
+		}
+		b.transfer("moved", Transfer{From: unescapeTag(args[1]), To: unescapeTag(args[2]), FromDisk: args[0], ToDisk: args[0]})
+	case "copy":
+		if len(args) < 4 {
+			return false
+		}
+		b.transfer("copied", Transfer{From: unescapeTag(args[1]), To: unescapeTag(args[3]), FromDisk: args[0], ToDisk: args[2]})
+	default:
+		return false
+	}
+	return true
+}
+
+// parseSummaryTag stores a single "summary:<key>:<value>" counter in d or s.
+func parseSummaryTag(d *DiffResult, s *Summary, key, value string) bool {
+	if key == "exit" {
+		s.Exit = value
+		return true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	switch key {
+	case "equal":
+		d.Equal = n
+	case "added", "removed", "updated", "moved", "copied", "restored":
+		d.report(key, n)
+	case "error_file":
+		s.FileErrors = n
+	case "error_io":
+		s.IOErrors = n
+	case "error_data":
+		s.DataErrors = n
+	case "error_recovered":
+		s.Recovered = n
+	case "error_unrecoverable":
+		s.Unrecoverable = n
+	default:
+		return false
+	}
+	return true
+}
+
+// unescapeTag reverses snapraid's tag escaping: "\d" is ':', "\n" and "\r" are
+// line breaks and "\\" is a backslash.
+func unescapeTag(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'd':
+			sb.WriteByte(':')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// readTagLog parses the tag log file at path, collecting diff entries in b unless b is nil.
+// It returns false if the file cannot be opened or contains no tags.
+func readTagLog(path string, b *diffBuilder) (tagReport, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return tagReport{}, false, nil
+	}
+	defer f.Close() // nolint:errcheck
+
+	return parseTags(f, b)
+}
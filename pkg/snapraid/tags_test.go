@@ -0,0 +1,135 @@
+package snapraid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Diff entries and counters", func(t *testing.T) {
+		t.Parallel()
+
+		log := strings.Join([]string{
+			"version:12.3",
+			"scan:add:d1:new.txt",
+			"scan:remove:d1:gone.txt",
+			"scan:update:d2:changed.txt",
+			"scan:restore:d2:back.txt",
+			"scan:move:d1:old/a.txt:new/a.txt",
+			"scan:copy:d1:src.txt:d2:dst.txt",
+			"summary:equal:7",
+			"summary:exit:diff",
+		}, "\n")
+
+		rep, ok, err := parseTags(strings.NewReader(log), &diffBuilder{})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, DiffResult{
+			Equal:    7,
+			Added:    []string{"new.txt"},
+			Removed:  []string{"gone.txt"},
+			Updated:  []string{"changed.txt"},
+			Restored: []string{"back.txt"},
+			Moved:    []string{"old/a.txt -> new/a.txt"},
+			Copied:   []string{"src.txt -> dst.txt"},
+			Entries: []DiffEntry{
+				{Change: "added", Disk: "d1", RelPath: "new.txt"},
+				{Change: "removed", Disk: "d1", RelPath: "gone.txt"},
+				{Change: "updated", Disk: "d2", RelPath: "changed.txt"},
+				{Change: "restored", Disk: "d2", RelPath: "back.txt"},
+				{Change: "moved", Disk: "d1", RelPath: "new/a.txt"},
+				{Change: "copied", Disk: "d2", RelPath: "dst.txt"},
+			},
+			Moves:  []Transfer{{From: "old/a.txt", To: "new/a.txt", FromDisk: "d1", ToDisk: "d1"}},
+			Copies: []Transfer{{From: "src.txt", To: "dst.txt", FromDisk: "d1", ToDisk: "d2"}},
+		}, rep.Diff)
+		assert.Equal(t, "diff", rep.Summary.Exit)
+	})
+
+	t.Run("Summary counters", func(t *testing.T) {
+		t.Parallel()
+
+		log := "summary:error_file:1\r\nsummary:error_io:2\r\nsummary:error_data:3\r\nsummary:exit:error\r\n"
+
+		rep, ok, err := parseTags(strings.NewReader(log), &diffBuilder{})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Summary{Exit: "error", FileErrors: 1, IOErrors: 2, DataErrors: 3}, rep.Summary)
+		assert.True(t, rep.Summary.HasErrors())
+	})
+
+	t.Run("Diff counters", func(t *testing.T) {
+		t.Parallel()
+
+		log := "scan:add:d1:a.txt\nsummary:equal:3\nsummary:added:1\nsummary:removed:0\nsummary:exit:diff\n"
+
+		rep, ok, err := parseTags(strings.NewReader(log), &diffBuilder{})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]int{"added": 1, "removed": 0}, rep.Diff.Reported)
+		assert.NoError(t, rep.Diff.verifyCounts())
+	})
+
+	t.Run("Summary only skips scan tags", func(t *testing.T) {
+		t.Parallel()
+
+		log := "scan:add:d1:a.txt\nscan:remove:d1:b.txt\nsummary:error_io:1\nsummary:exit:error\n"
+
+		rep, ok, err := parseTags(strings.NewReader(log), nil)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Summary{Exit: "error", IOErrors: 1}, rep.Summary)
+		assert.False(t, rep.Diff.HasChanges())
+		assert.Nil(t, rep.Diff.Entries)
+	})
+
+	t.Run("Read error", func(t *testing.T) {
+		t.Parallel()
+
+		log := "summary:error_io:1\nscan:add:d1:" + strings.Repeat("a", 2*1024*1024) + "\n"
+
+		rep, ok, err := parseTags(strings.NewReader(log), nil)
+		assert.EqualError(t, err, "failed to read snapraid log: bufio.Scanner: token too long")
+		assert.True(t, ok)
+		assert.Equal(t, 1, rep.Summary.IOErrors)
+	})
+
+	t.Run("No tags", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok, err := parseTags(strings.NewReader("Comparing...\nadd file.txt\n"), &diffBuilder{})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Missing log file", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok, err := readTagLog("/nonexistent/snapraid.log", &diffBuilder{})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestUnescapeTag(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"plain.txt":     "plain.txt",
+		`a\db.txt`:      "a:b.txt",
+		`line\nbreak`:   "line\nbreak",
+		`back\\slash`:   `back\slash`,
+		`trailing\`:     `trailing\`,
+		`mixed\d\\\d.x`: `mixed:\:.x`,
+	}
+	for in, want := range tests {
+		t.Run(in, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, want, unescapeTag(in))
+		})
+	}
+}
@@ -1,10 +1,23 @@
 package snapraid
 
-// Snapraid defines the five low‐level subcommand methods.
+import "context"
+
+// Keywords accepted by "snapraid scrub --plan" instead of a percentage.
+const (
+	ScrubPlanNew  = "new"  // ScrubPlanNew scrubs the blocks synced but never scrubbed.
+	ScrubPlanBad  = "bad"  // ScrubPlanBad scrubs the blocks marked bad.
+	ScrubPlanFull = "full" // ScrubPlanFull scrubs every block.
+)
+
+// Snapraid defines the low‐level subcommand methods.
+// Every method honors ctx: cancelling it interrupts the running snapraid child.
 type Snapraid interface {
-	Touch() error            // Touch runs `snapraid touch`
-	Diff() ([]string, error) // Diff runs `snapraid diff` and returns all output lines
-	Sync() error             // Sync runs `snapraid sync`
-	Scrub() error            // Scrub runs `snapraid scrub` with plan/older‐than flags
-	Smart() error            // Smart runs `snapraid smart`
+	Touch(ctx context.Context) error                                         // Touch runs `snapraid touch`
+	Diff(ctx context.Context) (DiffResult, error)                            // Diff runs `snapraid diff` and returns the parsed result
+	Sync(ctx context.Context, force []string) (*Summary, error)              // Sync runs `snapraid sync` with the given force options (e.g. "--force-zero") and returns its summary, if reported
+	Scrub(ctx context.Context, plan string, olderThan int) (*Summary, error) // Scrub runs `snapraid scrub` with the configured plan/older‐than flags, or with the given ones (e.g. "bad") if plan is set, and returns its summary, if reported
+	Fix(ctx context.Context) (*Summary, error)                               // Fix runs `snapraid fix -e` on the blocks marked bad and returns its summary, if reported
+	Check(ctx context.Context) (*Summary, error)                             // Check runs `snapraid check -e` on the blocks marked bad and returns its summary, if reported
+	Smart(ctx context.Context) (*SmartReport, error)                         // Smart runs `snapraid smart` and returns the parsed disk report
+	Status(ctx context.Context) (*StatusReport, error)                       // Status runs `snapraid status` and returns the parsed array status
 }
@@ -1,6 +1,7 @@
 package snapraid
 
 import (
+	"context"
 	"errors"
 	"os/exec"
 	"slices"
@@ -17,9 +18,53 @@ func isAcceptableExitCode(err error, allowed ...int) bool {
 }
 
 // runStep executes the given step function and records its duration via setDuration.
-func runStep(step func() error, setDuration func(time.Duration)) error {
+// It does not start the step if ctx is already done.
+func runStep(ctx context.Context, step func(context.Context) error, setDuration func(time.Duration)) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
 	t0 := time.Now()
-	err := step()
+	err := step(ctx)
 	setDuration(time.Since(t0))
 	return err
 }
+
+// withTimeout wraps step so that it runs under its own deadline of limit.
+// A limit <= 0 returns step unchanged.
+func withTimeout(name string, limit time.Duration, step func(context.Context) error) func(context.Context) error {
+	if limit <= 0 {
+		return step
+	}
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeoutCause(ctx, limit, &TimeoutError{Step: name, Limit: limit})
+		defer cancel()
+		return step(ctx)
+	}
+}
+
+// minStepBudget is the remaining run budget an optional step without its own timeout needs to be started.
+const minStepBudget = time.Minute
+
+// lacksBudget returns true if the time left until ctx's deadline is shorter than limit,
+// i.e. a step allowed to run for limit could not finish within the remaining run budget.
+// A step without a limit needs at least minStepBudget.
+func lacksBudget(ctx context.Context, limit time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	if limit <= 0 {
+		limit = minStepBudget
+	}
+	return time.Until(deadline) < limit
+}
+
+// resultStep adapts a step returning a value to the plain step signature,
+// storing the value of the latest attempt in dst.
+func resultStep[T any](step func(context.Context) (T, error), dst *T) func(context.Context) error {
+	return func(ctx context.Context) error {
+		v, err := step(ctx)
+		*dst = v
+		return err
+	}
+}
@@ -1,6 +1,7 @@
 package snapraid
 
 import (
+	"context"
 	"errors"
 	"os/exec"
 	"testing"
@@ -63,13 +64,13 @@ func TestRunStep(t *testing.T) {
 		called := false
 		var duration time.Duration
 
-		step := func() error {
+		step := func(ctx context.Context) error {
 			called = true
 			time.Sleep(10 * time.Millisecond)
 			return nil
 		}
 
-		err := runStep(step, func(d time.Duration) {
+		err := runStep(context.Background(), step, func(d time.Duration) {
 			duration = d
 		})
 
@@ -83,12 +84,12 @@ func TestRunStep(t *testing.T) {
 		wantErr := errors.New("step failed")
 		var duration time.Duration
 
-		step := func() error {
+		step := func(ctx context.Context) error {
 			time.Sleep(5 * time.Millisecond)
 			return wantErr
 		}
 
-		err := runStep(step, func(d time.Duration) {
+		err := runStep(context.Background(), step, func(d time.Duration) {
 			duration = d
 		})
 
@@ -96,3 +97,66 @@ func TestRunStep(t *testing.T) {
 		assert.GreaterOrEqual(t, duration.Milliseconds(), int64(5))
 	})
 }
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Zero limit returns step unchanged", func(t *testing.T) {
+		t.Parallel()
+		step := func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.False(t, ok)
+			return nil
+		}
+		assert.NoError(t, withTimeout("sync", 0, step)(context.Background()))
+	})
+
+	t.Run("Limit sets a deadline with timeout cause", func(t *testing.T) {
+		t.Parallel()
+		step := func(ctx context.Context) error {
+			<-ctx.Done()
+			return context.Cause(ctx)
+		}
+		err := withTimeout("sync", 10*time.Millisecond, step)(context.Background())
+
+		var timeoutErr *TimeoutError
+		assert.ErrorAs(t, err, &timeoutErr)
+		assert.Equal(t, "sync", timeoutErr.Step)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualError(t, err, "sync timed out after 10ms")
+	})
+}
+
+func TestLacksBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("No deadline", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, lacksBudget(context.Background(), time.Hour))
+	})
+
+	t.Run("No step limit needs the minimum budget", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		assert.False(t, lacksBudget(ctx, 0))
+
+		short, cancelShort := context.WithTimeout(context.Background(), time.Second)
+		defer cancelShort()
+		assert.True(t, lacksBudget(short, 0))
+	})
+
+	t.Run("Limit fits", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		assert.False(t, lacksBudget(ctx, time.Minute))
+	})
+
+	t.Run("Limit does not fit", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		assert.True(t, lacksBudget(ctx, time.Hour))
+	})
+}